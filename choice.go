@@ -0,0 +1,199 @@
+package goldcmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The value of a label that must be one of a fixed set of choices e.g.
+// "json", "yaml" or "table".
+type choiceValue struct {
+	value   string
+	choices []string
+	// descriptions of some of the choices for help and completion
+	descriptions map[string]string
+	// set if the choices are matched regardless of case
+	ignoreCase bool
+}
+
+// Get a choice value, or an error if there are no choices or a choice is
+// repeated.
+func newChoiceValue(choices []string) (*choiceValue, error) {
+	if len(choices) == 0 {
+		return nil, errors.New("expected at least one choice")
+	}
+	for k, choice := range choices {
+		if choice == "" {
+			return nil, errors.New("a choice cannot be empty")
+		}
+		if strInList(choice, choices[:k]) {
+			return nil, fmt.Errorf("the choice %q is repeated", choice)
+		}
+	}
+	return &choiceValue{choices: append([]string{}, choices...), descriptions: make(map[string]string)}, nil
+}
+
+// Get the choice matching the text, or an empty string if there is none.
+func (v *choiceValue) match(s string) string {
+	for _, choice := range v.choices {
+		if choice == s || (v.ignoreCase && strings.EqualFold(choice, s)) {
+			return choice
+		}
+	}
+	return ""
+}
+
+// Set the value to one of the choices. If the choices are matched regardless
+// of case, the value is the choice as it was declared, so '--format JSON'
+// gives "json".
+func (v *choiceValue) Set(s string) error {
+	choice := v.match(s)
+	if choice != "" {
+		v.value = choice
+		return nil
+	}
+	word := s
+	candidates := v.choices
+	if v.ignoreCase {
+		word = strings.ToLower(s)
+		candidates = make([]string, 0, len(v.choices))
+		for _, c := range v.choices {
+			candidates = append(candidates, strings.ToLower(c))
+		}
+	}
+	if closest := suggest(word, candidates); closest != "" {
+		for k, c := range candidates {
+			if c == closest {
+				return fmt.Errorf("not one of the choices, did you mean %q?", v.choices[k])
+			}
+		}
+	}
+	return errors.New("not one of the choices")
+}
+
+func (v *choiceValue) String() string   { return v.value }
+func (v *choiceValue) Type() string     { return "{" + strings.Join(v.choices, ",") + "}" }
+func (v *choiceValue) get() interface{} { return v.value }
+
+// Get the choices as completion candidates, with their descriptions.
+func (v *choiceValue) candidates() []Completion {
+	candidates := make([]Completion, 0, len(v.choices))
+	for _, choice := range v.choices {
+		candidates = append(candidates, Completion{Value: choice, Description: v.descriptions[choice]})
+	}
+	return candidates
+}
+
+// Get the specs of the choices.
+func (v *choiceValue) specs() []ChoiceSpec {
+	specs := make([]ChoiceSpec, 0, len(v.choices))
+	for _, choice := range v.choices {
+		specs = append(specs, ChoiceSpec{Value: choice, Documentation: v.descriptions[choice]})
+	}
+	return specs
+}
+
+// Get the help lines describing the choices, one for each choice with a
+// description.
+func (v *choiceValue) helpString() string {
+	s := ""
+	for _, choice := range v.choices {
+		if doc, ok := v.descriptions[choice]; ok {
+			s = s + "\t  " + choice + ": " + doc + "\n"
+		}
+	}
+	return s
+}
+
+// Get the choice value of a label or positional argument, or nil if it does
+// not have one.
+func (h *SubcommandHandler) choiceOf(label string) *choiceValue {
+	for _, cp := range []*commandParser{h.argparser, h.paramparser, h.posparser} {
+		if l := cp.labelOf(label); l != nil {
+			v, _ := l.value.(*choiceValue)
+			return v
+		}
+	}
+	return nil
+}
+
+// Add a string argument to the subcommand, which must be one of the given
+// choices e.g. '--format json'. Other values are rejected with the list of
+// choices. The value is read with GetStr.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddChoiceArg(aliases []string, doc string, choices []string) error {
+	value, err := newChoiceValue(choices)
+	if err != nil {
+		return err
+	}
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.argparser.addLabel(aliases, doc, value)
+	return nil
+}
+
+// Add a string parameter to the subcommand, which must be one of the given
+// choices, with a default value, which must be one of the choices too.
+func (h *SubcommandHandler) AddChoiceParamWithDefault(aliases []string, doc string, choices []string, deflt string) error {
+	value, err := newChoiceValue(choices)
+	if err != nil {
+		return err
+	}
+	if err := value.Set(deflt); err != nil {
+		return fmt.Errorf("invalid default %q: %s", deflt, err)
+	}
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.paramparser.addLabelWithDefault(aliases, doc, value)
+	return nil
+}
+
+// Add a required string positional argument to the subcommand, which must
+// be one of the given choices. The value is read with GetStr.
+func (h *SubcommandHandler) AddChoicePositional(name string, doc string, choices []string) error {
+	value, err := newChoiceValue(choices)
+	if err != nil {
+		return err
+	}
+	return h.addPositional(&positional{name: name, documentation: doc}, value)
+}
+
+// Set whether the choices of a label or positional argument are matched
+// regardless of case, so e.g. '--format JSON' is the same as '--format json'.
+func (h *SubcommandHandler) SetIgnoreCase(label string, ignore bool) error {
+	v := h.choiceOf(label)
+	if v == nil {
+		return fmt.Errorf("the label \"%s\" does not have choices", label)
+	}
+	if ignore {
+		for k, choice := range v.choices {
+			for _, other := range v.choices[:k] {
+				if strings.EqualFold(choice, other) {
+					return fmt.Errorf("the choices %q and %q differ only in case", other, choice)
+				}
+			}
+		}
+	}
+	v.ignoreCase = ignore
+	return nil
+}
+
+// Describe one of the choices of a label or positional argument. The
+// description is shown in help and by shell completion.
+func (h *SubcommandHandler) DescribeChoice(label string, choice string, doc string) error {
+	v := h.choiceOf(label)
+	if v == nil {
+		return fmt.Errorf("the label \"%s\" does not have choices", label)
+	}
+	if !strInList(choice, v.choices) {
+		return fmt.Errorf("unknown choice %q for \"%s\"", choice, label)
+	}
+	v.descriptions[choice] = doc
+	return nil
+}
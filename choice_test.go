@@ -0,0 +1,143 @@
+package goldcmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleChoiceCli(t *testing.T) Cli {
+	cli := NewCli("1.0", "A CLI with choices.")
+	sub, _ := NewSubcommandHandler("report", "Print a report.")
+	for _, err := range []error{
+		sub.AddChoiceArg([]string{"format", "f"}, "the output format", []string{"json", "yaml", "table"}),
+		sub.AddChoiceParamWithDefault([]string{"log-level"}, "how much to log", []string{"debug", "info", "warn", "error"}, "info"),
+		sub.AddChoicePositional("section", "the section to print", []string{"summary", "details"}),
+		sub.SetIgnoreCase("log-level", true),
+		sub.DescribeChoice("format", "json", "for machines"),
+		sub.DescribeChoice("format", "table", "for people"),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sub.Handle(func(h *SubcommandHandler) {
+		format, _ := h.GetStr("format")
+		level, _ := h.GetStr("log-level")
+		section, _ := h.GetStr("section")
+		fmt.Fprintf(h.Stdout(), "%s %s %s", format, level, section)
+	})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func TestChoices(t *testing.T) {
+	cli := sampleChoiceCli(t)
+	code, out, errOut := execute(&cli, "report", "--format", "yaml", "summary")
+	if want := "yaml info summary"; code != 0 || out != want {
+		t.Fatalf("expected %q, got %d %q %q", want, code, out, errOut)
+	}
+	code, out, errOut = execute(&cli, "report", "-f=json", "--log-level", "WARN", "details")
+	if want := "json warn details"; code != 0 || out != want {
+		t.Fatalf("expected %q, got %d %q %q", want, code, out, errOut)
+	}
+}
+
+func TestChoiceErrors(t *testing.T) {
+	cli := sampleChoiceCli(t)
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"report", "--format", "jsno", "summary"},
+			`invalid value "jsno" for --format, expected {json,yaml,table}: not one of the choices, did you mean "json"?`},
+		{[]string{"report", "--format", "JSON", "summary"},
+			`invalid value "JSON" for --format, expected {json,yaml,table}: not one of the choices`},
+		{[]string{"report", "--format", "json", "--log-level", "Warning", "summary"},
+			`expected {debug,info,warn,error}: not one of the choices, did you mean "warn"?`},
+		{[]string{"report", "--format", "json", "all"},
+			`invalid value "all" for <section>, expected {summary,details}: not one of the choices`},
+	}
+	for _, test := range tests {
+		if code, _, errOut := execute(&cli, test.args...); code != 2 || !strings.Contains(errOut, test.err) {
+			t.Fatalf("%v: expected %q, got %q", test.args, test.err, errOut)
+		}
+	}
+
+	sub, _ := NewSubcommandHandler("report", "Print a report.")
+	if err := sub.AddChoiceArg([]string{"format"}, "", nil); err == nil {
+		t.Fatalf("a choice argument without choices should be an error")
+	}
+	if err := sub.AddChoiceParamWithDefault([]string{"format"}, "", []string{"json", "yaml"}, "xml"); err == nil {
+		t.Fatalf("a default that is not a choice should be an error")
+	}
+	sub.AddChoiceArg([]string{"mode"}, "", []string{"fast", "FAST"})
+	if err := sub.SetIgnoreCase("mode", true); err == nil {
+		t.Fatalf("choices that differ only in case should be an error")
+	}
+	if err := sub.DescribeChoice("mode", "slow", "doc"); err == nil {
+		t.Fatalf("describing an unknown choice should be an error")
+	}
+}
+
+func TestChoiceHelp(t *testing.T) {
+	cli := sampleChoiceCli(t)
+	_, out, _ := execute(&cli, "help", "report")
+	for _, want := range []string{
+		"usage: app report --format {json,yaml,table} [OPTIONS] <section>",
+		" <section>\tthe section to print ({summary,details})\n",
+		" --format, --f {json,yaml,table}\tthe output format\n\t  json: for machines\n\t  table: for people\n",
+		" --log-level {debug,info,warn,error}\thow much to log\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+}
+
+func TestChoiceCompletion(t *testing.T) {
+	cli := sampleChoiceCli(t)
+	for _, c := range []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"report", "--format", ""}, "json\tfor machines\nyaml\ntable\tfor people\n:1\n"},
+		{[]string{"report", "--log-level=w"}, "--log-level=warn\n:1\n"},
+		{[]string{"report", "--format", "json", "s"}, "summary\n:1\n"},
+	} {
+		var b bytes.Buffer
+		cli.complete(&b, c.words)
+		if b.String() != c.want {
+			t.Fatalf("%v: expected %q, got %q", c.words, c.want, b.String())
+		}
+	}
+}
+
+func TestChoiceSpec(t *testing.T) {
+	cli := sampleChoiceCli(t)
+	spec := cli.Spec()
+	sub := spec.Subcommands[0]
+	format, level := sub.Labels[0], sub.Labels[1]
+	if format.Type != "choice" || len(format.Choices) != 3 || format.Choices[0] != (ChoiceSpec{Value: "json", Documentation: "for machines"}) {
+		t.Fatalf("unexpected label %+v", format)
+	}
+	if !level.IgnoreCase || level.Default != "info" || sub.Positionals[0].Type != "choice" {
+		t.Fatalf("unexpected spec %+v", sub)
+	}
+	var b bytes.Buffer
+	writeJSON(&b, spec)
+	loaded, err := NewCliFromJSON(b.Bytes(), Handlers{"report": func(h *SubcommandHandler) {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.Spec(), spec) {
+		t.Fatalf("the loaded CLI differs from the original:\n%+v\n%+v", loaded.Spec(), spec)
+	}
+	_, err = NewCliFromJSON([]byte(`{"subcommands": [{"name": "report", "labels": [
+		{"name": "format", "type": "str", "choices": ["json"]}]}]}`), Handlers{"report": func(h *SubcommandHandler) {}})
+	if err == nil || !strings.Contains(err.Error(), "only a flag of type choice has choices") {
+		t.Fatalf("expected an error for choices on a string flag, got %v", err)
+	}
+}
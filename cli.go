@@ -1,20 +1,42 @@
 package goldcmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // The CLI "server" object
 type Cli struct {
 	// Brief documentation of the CLI app
 	documentation string
-	// TODO(gs): The version is not publically exposed. Add 'version' subcommand
-	// preset. Also check that 'help' is a reserved subcommand.
-	// Version of the CLI app
+	// Version of the CLI app, printed by the 'version' subcommand
 	version string
 	// The subcommands available with this CLI
 	subcommands []*SubcommandHandler
+	// If set, flags unknown to a subcommand are passed to it instead of
+	// being reported as errors
+	allowUnknownFlags bool
+	// If set, the 'version' subcommand also prints the build information
+	useBuildInfo bool
+	// If set, the CLI definition is validated before every execution
+	debug bool
+	// The name of the program, or an empty string to use the name the CLI
+	// was invoked with
+	name string
+	// If set, every parameter is bound to an environment variable named
+	// after the prefix and its label
+	envPrefix string
+	// The function used to look up environment variables, or nil to use
+	// os.LookupEnv
+	lookupEnv func(key string) (string, bool)
+	// The configuration files to read parameters from, or nil if the CLI
+	// does not read any
+	configFiles *ConfigFiles
 }
 
 // Create a new Cli instance.
@@ -33,73 +55,375 @@ func (cli *Cli) HandleSubcommand(subcmd *SubcommandHandler) {
 	cli.subcommands = append(cli.subcommands, subcmd)
 }
 
-// Print a help message.
-// The variable sub is either one of the subcommands or an empty string.
-// If it matches a a subcommand, then the help message for that subcommand
-// is printed. If it is empty (or does not match a subcommand), then the
-// help message for the CLI app is printed.
-func (cli *Cli) printHelp(sub string) {
-	if sub != "" {
-		for _, subcmd := range cli.subcommands {
-			if subcmd.name == sub {
-				fmt.Printf("%s\n\n", subcmd.documentation)
-				subcmd.printArgumentHelp()
-				subcmd.printOptionHelp()
-				subcmd.printExampleHelp()
-				return
+// Set the name of the program, as used in help messages and generated
+// documentation. By default, the name the CLI was invoked with is used.
+func (cli *Cli) SetName(name string) {
+	cli.name = name
+}
+
+// Get the name of the program for a command line, in the same form as
+// os.Args, or for the current process if `args` is empty.
+func (cli *Cli) progName(args []string) string {
+	if cli.name != "" {
+		return cli.name
+	}
+	if len(args) == 0 {
+		args = os.Args
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return filepath.Base(args[0])
+}
+
+// Allow or forbid flags that a subcommand does not know about.
+//
+// Unknown flags are errors by default. If they are allowed, they are made
+// available to the handler through SubcommandHandler.UnknownFlags instead,
+// which is useful for subcommands that wrap other programs. The parser
+// cannot know whether an unknown flag takes a value, so in '--foo bar' only
+// '--foo' is an unknown flag, and 'bar' is kept in
+// SubcommandHandler.RemainingArgs if no positional argument takes it.
+func (cli *Cli) AllowUnknownFlags(allow bool) {
+	cli.allowUnknownFlags = allow
+}
+
+// Print the help message for the CLI app to `w`.
+func (cli *Cli) printHelp(w io.Writer) {
+	fmt.Fprintf(w, "%s\n\n", cli.documentation)
+	fmt.Fprintf(w, "SUBCOMMANDS\n")
+	for _, subcmd := range visible(cli.allSubcommands()) {
+		fmt.Fprintf(w, "  %s\t%s\n", subcmd.name, subcmd.documentation)
+	}
+	fmt.Fprintf(w, "  help\tthis help message\n\n")
+	fmt.Fprintf(w, "OPTIONS\n")
+	if cli.configFiles != nil {
+		fmt.Fprintf(w, "  --config <path>\tread parameters from a configuration file\n")
+	}
+	fmt.Fprintf(w, "  --show-config\tprint the value of every flag and where it comes from, instead of running the subcommand\n\n")
+	fmt.Fprintf(w, "Get help with a subcommand by passing it as an argument to the 'help' subcommand.\n")
+}
+
+// Return true if a token asks for help.
+func isHelpToken(token string) bool {
+	return token == "--help" || token == "help" || token == "-h"
+}
+
+// Either print help, or run a subcommand, and return the exit status.
+//
+// The argument `args` is the full command line, including the program name,
+// in the same form as os.Args. Help and handler output is written to `stdout`
+// and `stderr`, and handlers may read from `stdin`. The context `ctx` is made
+// available to the handler through SubcommandHandler.Context.
+//
+// Unlike Run, Execute never exits the process, so it can be used to test a
+// whole CLI or to embed several Cli instances in one program.
+func (cli *Cli) Execute(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	prog := cli.progName(args)
+	cli.configureEnv()
+	if cli.debugging() {
+		if err := cli.Validate(); err != nil {
+			return printDefinitionErrors(stderr, err)
+		}
+	}
+	if len(args) < 2 {
+		cli.printHelp(stdout)
+		return 0
+	}
+	if args[1] == "__complete" {
+		// the hidden protocol used by the shell completion scripts
+		cli.complete(stdout, args[2:])
+		return 0
+	}
+	// e.g. 'app --config ./app.toml divide', and 'app divide --show-config'
+	// once the subcommand is known
+	cl := newCommandLine(args)
+	lead := cli.leadingGlobalFlags(args)
+	showSources, explicitConfig, err := cli.extractGlobalFlags(cl, func(index int) bool { return index < lead })
+	if err != nil {
+		return cli.usageError(stderr, prog, err)
+	}
+	args = cl.args
+	if len(args) < 2 {
+		cli.printHelp(stdout)
+		return 0
+	}
+	if args[1] == "--version" || args[1] == "-V" {
+		// e.g. 'app --version --json' is the same as 'app version --json'
+		args = append([]string{args[0], "version"}, args[2:]...)
+	}
+	if isHelpToken(args[1]) {
+		// e.g. 'app help db migrate', or 'app help --json db migrate'
+		words := make([]string, 0, len(args))
+		asJSON := false
+		for _, word := range args {
+			if word == "--json" {
+				asJSON = true
+			} else {
+				words = append(words, word)
 			}
 		}
+		subcmd, k := cli.resolve(words, 2)
+		if k < len(words) {
+			return cli.usageError(stderr, prog, unknownSubcommandError(words[k], cli.childNames(subcmd)))
+		}
+		path := append([]string{prog}, words[2:]...)
+		switch {
+		case asJSON && subcmd == nil:
+			writeJSON(stdout, cli.spec(prog))
+		case asJSON:
+			writeJSON(stdout, subcmd.spec(path))
+		case subcmd == nil:
+			cli.printHelp(stdout)
+		default:
+			subcmd.printHelp(stdout, path)
+		}
+		return 0
+	}
+	subcmd, k := cli.resolve(args, 1)
+	if subcmd == nil {
+		return cli.usageError(stderr, prog, unknownSubcommandError(args[1], cli.childNames(nil)))
+	}
+	unknown := subcmd.unknownFlagIndices(cl, k)
+	more, config, err := cli.extractGlobalFlags(cl, func(index int) bool { return unknown[index] })
+	if err != nil {
+		return cli.usageError(stderr, prog, err)
+	}
+	showSources = showSources || more
+	if config != "" {
+		explicitConfig = config
+	}
+	args = cl.args
+	path := append([]string{prog}, args[1:k]...)
+	for j := k; j < len(args); j++ {
+		if (args[j] == "--help" || args[j] == "-h") && unknown[cl.index[j]] {
+			// e.g. 'app db migrate --help' or 'app cp a b --help'
+			subcmd.printHelp(stdout, path)
+			return 0
+		}
+	}
+	if len(subcmd.subcommands) > 0 && len(subcmd.positionals) == 0 && k < len(args) && !strings.HasPrefix(args[k], "-") {
+		// a word after a command group that is not one of its subcommands
+		return cli.usageError(stderr, prog, unknownSubcommandError(args[k], cli.childNames(subcmd)))
 	}
-	fmt.Printf("%s\n\n", cli.documentation)
-	fmt.Printf("SUBCOMMANDS\n")
-	for _, subcmd := range cli.subcommands {
-		fmt.Printf("  %s\t%s\n", subcmd.name, subcmd.documentation)
+	if cli.configFiles != nil && findSubcommand(cli.subcommands, args[1]) != nil {
+		values, err := cli.readConfig(explicitConfig)
+		if err != nil {
+			return printConfigErrors(stderr, err)
+		}
+		cli.applyConfig(values)
 	}
-	fmt.Printf("  help\tthis help message\n\n")
-	fmt.Printf("Get help with a subcommand with by passing it as an argument to the 'help' subcommand.\n")
+	subcmd.showSources = showSources
+	subcmd.tokenIndex = cl.index
+	return subcmd.execute(ctx, args, path, cli.allowUnknownFlags, stdin, stdout, stderr)
 }
 
-// Either print help, or run a subcommand.
-func (cli *Cli) Run() {
-	if len(os.Args) == 1 {
-		cli.printHelp("")
-		return
-	} else if len(os.Args) == 2 {
-		cmd := os.Args[1]
-		if cmd == "--help" || cmd == "help" || cmd == "-h" {
-			cli.printHelp("")
-			return
-		} else {
-			for _, subcmd := range cli.subcommands {
-				if subcmd.name == cmd {
-					// parse command line arguments and pass them to the subcommand
-					subcmd.parseFlags(os.Args)
-					// if the values are valid, then run the subcommand's handle function
-					subcmd.handle(subcmd)
-					return
-				}
-			}
-			cli.printHelp("")
-			return
+// A command line, and the index of each of its tokens in the original
+// command line, from before the global flags were removed.
+type commandLine struct {
+	args  []string
+	index []int
+}
+
+// Create a command line from the tokens given to Cli.Execute.
+func newCommandLine(args []string) *commandLine {
+	index := make([]int, len(args))
+	for k := range index {
+		index[k] = k
+	}
+	return &commandLine{args: args, index: index}
+}
+
+// Remove a global flag from the command line, where it can be given as any
+// token before "--" for which `eligible` is true, given the index of the
+// token in the original command line. It returns the value of the flag if it
+// takes one, and whether the flag was given.
+func (cl *commandLine) extract(name string, takesValue bool, eligible func(index int) bool) (string, bool, error) {
+	args := make([]string, 0, len(cl.args))
+	index := make([]int, 0, len(cl.index))
+	value := ""
+	given := false
+	for k := 0; k < len(cl.args); k++ {
+		arg := cl.args[k]
+		if arg == "--" && k > 0 {
+			args = append(args, cl.args[k:]...)
+			index = append(index, cl.index[k:]...)
+			break
 		}
-	} else {
-		cmd := os.Args[1]
-		if cmd == "--help" || cmd == "help" || cmd == "-h" {
-			arg := os.Args[2]
-			cli.printHelp(arg)
-			return
-		} else {
-			for _, subcmd := range cli.subcommands {
-				if subcmd.name == cmd {
-					// parse command line arguments and pass them to the subcommand
-					subcmd.parseFlags(os.Args)
-					// if the values are valid, then run the subcommand's handle function
-					subcmd.handle(subcmd)
-					return
-				}
+		label := strings.TrimLeft(arg, "-")
+		dashes := len(arg) - len(label)
+		switch {
+		case k == 0 || dashes < 1 || dashes > 2 || !eligible(cl.index[k]):
+			args = append(args, arg)
+			index = append(index, cl.index[k])
+		case label == name && takesValue:
+			if k+1 == len(cl.args) {
+				return "", false, fmt.Errorf("missing value for --%s", name)
 			}
-			cli.printHelp("")
-			return
+			value = cl.args[k+1]
+			given = true
+			k++
+		case label == name:
+			given = true
+		case takesValue && strings.HasPrefix(label, name+"="):
+			value = label[len(name)+1:]
+			given = true
+		default:
+			args = append(args, arg)
+			index = append(index, cl.index[k])
 		}
 	}
+	cl.args = args
+	cl.index = index
+	return value, given, nil
+}
+
+// Get the index of the first token of a command line after the global flags
+// given before the subcommand words e.g. 2 for 'app --show-config divide'.
+func (cli *Cli) leadingGlobalFlags(args []string) int {
+	k := 1
+	for k < len(args) {
+		label := strings.TrimLeft(args[k], "-")
+		switch {
+		case label == args[k]:
+			return k
+		case label == "show-config" || (cli.configFiles != nil && strings.HasPrefix(label, "config=")):
+			k++
+		case cli.configFiles != nil && label == "config":
+			k += 2
+		default:
+			return k
+		}
+	}
+	return k
+}
+
+// Remove the global flags --show-config and, if the CLI reads configuration
+// files, --config from the tokens of a command line for which `eligible` is
+// true. It returns whether --show-config was given and the value of
+// --config.
+func (cli *Cli) extractGlobalFlags(cl *commandLine, eligible func(index int) bool) (bool, string, error) {
+	_, showSources, _ := cl.extract("show-config", false, eligible)
+	if cli.configFiles == nil {
+		return showSources, "", nil
+	}
+	config, _, err := cl.extract("config", true, eligible)
+	return showSources, config, err
+}
+
+// Print an error about the top-level command line to `w`, and return the
+// exit status for it.
+func (cli *Cli) usageError(w io.Writer, prog string, err error) int {
+	fmt.Fprintf(w, "error: %s\n", err)
+	fmt.Fprintf(w, "Run '%s help' for usage.\n", prog)
+	return 2
+}
+
+// Get the names of the children of a subcommand, or of the top-level
+// subcommands (including 'help') if the subcommand is nil.
+func (cli *Cli) childNames(subcmd *SubcommandHandler) []string {
+	if subcmd != nil {
+		return subcmd.subcommandNames()
+	}
+	names := make([]string, 0)
+	for _, subcmd := range visible(cli.allSubcommands()) {
+		names = append(names, subcmd.name)
+	}
+	return append(names, "help")
+}
+
+// Get the subcommands built into every CLI.
+func (cli *Cli) builtinSubcommands() []*SubcommandHandler {
+	return []*SubcommandHandler{cli.versionSubcommand(), cli.completionSubcommand(), cli.genManSubcommand(), cli.genDocsSubcommand()}
+}
+
+// Get the subcommands added to the CLI followed by the built-in ones.
+func (cli *Cli) allSubcommands() []*SubcommandHandler {
+	all := make([]*SubcommandHandler, 0, len(cli.subcommands)+1)
+	all = append(all, cli.subcommands...)
+	return append(all, cli.builtinSubcommands()...)
+}
+
+// Call `f` for every visible subcommand of the CLI, parents before their
+// children, with the program name `prog` and the command words leading to the
+// subcommand.
+func (cli *Cli) walk(prog string, f func(path []string, h *SubcommandHandler)) {
+	var walk func(path []string, subcmds []*SubcommandHandler)
+	walk = func(path []string, subcmds []*SubcommandHandler) {
+		for _, subcmd := range visible(subcmds) {
+			p := append(append([]string{}, path...), subcmd.name)
+			f(p, subcmd)
+			walk(p, subcmd.subcommands)
+		}
+	}
+	walk([]string{prog}, cli.allSubcommands())
+}
+
+// Get the subcommands of a list that are not hidden.
+func visible(subcmds []*SubcommandHandler) []*SubcommandHandler {
+	ret := make([]*SubcommandHandler, 0, len(subcmds))
+	for _, subcmd := range subcmds {
+		if !subcmd.hidden {
+			ret = append(ret, subcmd)
+		}
+	}
+	return ret
+}
+
+// Find a subcommand by name in a list, or return nil if there is no such
+// subcommand.
+func findSubcommand(subcmds []*SubcommandHandler, name string) *SubcommandHandler {
+	for _, subcmd := range subcmds {
+		if subcmd.name == name {
+			return subcmd
+		}
+	}
+	return nil
+}
+
+// Walk the subcommand tree along the command words of `args`, starting at
+// index `start`. The deepest matching subcommand is returned (or nil if the
+// first word does not match) along with the index of the first token after
+// the command words.
+func (cli *Cli) resolve(args []string, start int) (*SubcommandHandler, int) {
+	var subcmd *SubcommandHandler
+	children := cli.allSubcommands()
+	k := start
+	for k < len(args) {
+		next := findSubcommand(children, args[k])
+		if next == nil {
+			break
+		}
+		subcmd = next
+		children = next.subcommands
+		k++
+	}
+	return subcmd, k
+}
+
+// Parse a command line, in the same form as os.Args, for one of the CLI's
+// subcommands without running the subcommand.
+//
+// The deepest subcommand named by the command line is returned along with any
+// error. Problems with the command line are reported together in an
+// ErrorList, where tokens that could not be used are described by
+// *ParseError values.
+func (cli *Cli) Parse(args []string) (*SubcommandHandler, error) {
+	if len(args) < 2 {
+		return nil, errors.New("no subcommand given")
+	}
+	cli.configureEnv()
+	subcmd, k := cli.resolve(args, 1)
+	if subcmd == nil {
+		return nil, unknownSubcommandError(args[1], cli.childNames(nil))
+	}
+	// no global flags were removed, so the tokens keep their index
+	subcmd.tokenIndex = nil
+	return subcmd, subcmd.parseFlags(args, k, cli.allowUnknownFlags)
+}
+
+// Either print help, or run a subcommand, using the process arguments and
+// standard streams. The process exits with the status returned by Execute.
+func (cli *Cli) Run() {
+	os.Exit(cli.Execute(context.Background(), os.Args, os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,268 @@
+package goldcmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleCli(t *testing.T) Cli {
+	cli := NewCli("1.2.3", "A sample CLI app.")
+	sub, err := NewSubcommandHandler("greet", "Greet someone.")
+	if err != nil {
+		t.Fatalf("could not create subcommand: %v", err)
+	}
+	if err := sub.AddStrArg([]string{"name", "n"}, "who to greet"); err != nil {
+		t.Fatalf("could not add argument: %v", err)
+	}
+	if err := sub.AddIntParamWithDefault([]string{"times"}, "how many times to greet", 1); err != nil {
+		t.Fatalf("could not add parameter: %v", err)
+	}
+	sub.Handle(func(h *SubcommandHandler) {
+		name, _ := h.GetStr("name")
+		times, _ := h.GetInt("times")
+		for i := 0; i < times; i++ {
+			fmt.Fprintf(h.Stdout(), "hello %s\n", name)
+		}
+		if name == "nobody" {
+			fmt.Fprintf(h.Stderr(), "nobody is here\n")
+			h.SetExitCode(3)
+		}
+	})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func execute(cli *Cli, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := cli.Execute(context.Background(), append([]string{"app"}, args...), strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestExecuteRunsHandler(t *testing.T) {
+	cli := sampleCli(t)
+	code, out, errOut := execute(&cli, "greet", "--name", "world", "--times=2")
+	if code != 0 {
+		t.Fatalf("exit status should be 0, got %d", code)
+	}
+	if out != "hello world\nhello world\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if errOut != "" {
+		t.Fatalf("unexpected error output %q", errOut)
+	}
+}
+
+func TestExecuteExitCode(t *testing.T) {
+	cli := sampleCli(t)
+	code, _, errOut := execute(&cli, "greet", "-n", "nobody")
+	if code != 3 {
+		t.Fatalf("exit status should be 3, got %d", code)
+	}
+	if errOut != "nobody is here\n" {
+		t.Fatalf("unexpected error output %q", errOut)
+	}
+}
+
+func TestExecuteIsRepeatable(t *testing.T) {
+	cli := sampleCli(t)
+	execute(&cli, "greet", "--name", "world", "--times", "3")
+	_, out, _ := execute(&cli, "greet", "--name", "again")
+	if out != "hello again\n" {
+		t.Fatalf("parameters should be reset between runs, got %q", out)
+	}
+}
+
+func TestExecuteHelp(t *testing.T) {
+	cli := sampleCli(t)
+	for _, args := range [][]string{{}, {"help"}, {"-h"}, {"--help"}} {
+		code, out, _ := execute(&cli, args...)
+		if code != 0 {
+			t.Fatalf("help should exit with status 0, got %d", code)
+		}
+		if !strings.Contains(out, "SUBCOMMANDS") || !strings.Contains(out, "greet") {
+			t.Fatalf("unexpected help output %q", out)
+		}
+	}
+	_, out, _ := execute(&cli, "help", "greet")
+	if !strings.Contains(out, "Greet someone.") || !strings.Contains(out, "--times") {
+		t.Fatalf("unexpected subcommand help output %q", out)
+	}
+}
+
+func TestExecuteMissingAndInvalidArguments(t *testing.T) {
+	cli := sampleCli(t)
+	code, out, errOut := execute(&cli, "greet", "--times", "many")
+	if code != 2 {
+		t.Fatalf("exit status should be 2, got %d", code)
+	}
+	if out != "" {
+		t.Fatalf("the handler should not run, got output %q", out)
+	}
+	for _, want := range []string{
+		"missing required argument --name",
+		"invalid value \"many\" for --times",
+		"usage: app greet --name <str> [OPTIONS]",
+	} {
+		if !strings.Contains(errOut, want) {
+			t.Fatalf("error output should contain %q, got %q", want, errOut)
+		}
+	}
+}
+
+func TestExecuteUnknownFlag(t *testing.T) {
+	cli := sampleCli(t)
+	code, out, errOut := execute(&cli, "greet", "--name", "x", "--tims", "2")
+	if code != 2 || out != "" {
+		t.Fatalf("an unknown flag should fail without running the handler")
+	}
+	if !strings.Contains(errOut, "unknown flag --tims, did you mean --times?") {
+		t.Fatalf("unexpected error output %q", errOut)
+	}
+}
+
+func TestExecuteAllowUnknownFlags(t *testing.T) {
+	cli := sampleCli(t)
+	cli.AllowUnknownFlags(true)
+	var unknown []string
+	cli.subcommands[0].Handle(func(h *SubcommandHandler) {
+		unknown = h.UnknownFlags()
+	})
+	code, _, _ := execute(&cli, "greet", "--name", "x", "--color=red", "-v")
+	if code != 0 {
+		t.Fatalf("unknown flags should be allowed, got status %d", code)
+	}
+	if len(unknown) != 2 || unknown[0] != "--color=red" || unknown[1] != "-v" {
+		t.Fatalf("unexpected unknown flags %v", unknown)
+	}
+}
+
+func TestExecuteAllowUnknownFlagsWithValues(t *testing.T) {
+	cli := sampleCli(t)
+	cli.AllowUnknownFlags(true)
+	var unknown, remaining []string
+	cli.subcommands[0].Handle(func(h *SubcommandHandler) {
+		unknown = h.UnknownFlags()
+		remaining = h.RemainingArgs()
+	})
+	code, _, errOut := execute(&cli, "greet", "--foo", "bar", "--name", "x", "baz", "--", "qux")
+	if code != 0 {
+		t.Fatalf("the values of unknown flags should be allowed, got status %d %q", code, errOut)
+	}
+	if !reflect.DeepEqual(unknown, []string{"--foo"}) || !reflect.DeepEqual(remaining, []string{"bar", "baz", "qux"}) {
+		t.Fatalf("unexpected unknown flags %v and remaining arguments %v", unknown, remaining)
+	}
+}
+
+func TestExecuteUnknownSubcommand(t *testing.T) {
+	cli := sampleCli(t)
+	for _, args := range [][]string{{"gret"}, {"help", "gret"}} {
+		code, out, errOut := execute(&cli, args...)
+		if code != 2 || out != "" {
+			t.Fatalf("an unknown subcommand should fail, got status %d", code)
+		}
+		if !strings.Contains(errOut, "unknown subcommand \"gret\", did you mean \"greet\"?") {
+			t.Fatalf("unexpected error output %q", errOut)
+		}
+	}
+}
+
+func sampleTreeCli(t *testing.T) (Cli, *[]string) {
+	ran := make([]string, 0)
+	cli := NewCli("1.0", "A CLI with command groups.")
+	db, _ := NewSubcommandHandler("db", "Manage the database.")
+	migrate, _ := NewSubcommandHandler("migrate", "Run migrations.")
+	migrate.Handle(func(h *SubcommandHandler) {
+		ran = append(ran, "migrate")
+	})
+	up, _ := NewSubcommandHandler("up", "Apply migrations.")
+	up.AddIntParamWithDefault([]string{"steps"}, "how many migrations to apply", 1)
+	up.Handle(func(h *SubcommandHandler) {
+		steps, _ := h.GetInt("steps")
+		ran = append(ran, fmt.Sprintf("up %d", steps))
+	})
+	migrate.HandleSubcommand(up)
+	db.HandleSubcommand(migrate)
+	cli.HandleSubcommand(db)
+	return cli, &ran
+}
+
+func TestExecuteTree(t *testing.T) {
+	cli, ran := sampleTreeCli(t)
+	if code, _, errOut := execute(&cli, "db", "migrate", "up", "--steps", "3"); code != 0 {
+		t.Fatalf("unexpected failure %q", errOut)
+	}
+	if code, _, errOut := execute(&cli, "db", "migrate"); code != 0 {
+		t.Fatalf("unexpected failure %q", errOut)
+	}
+	if strings.Join(*ran, ",") != "up 3,migrate" {
+		t.Fatalf("unexpected handlers run %v", *ran)
+	}
+	code, _, errOut := execute(&cli, "db")
+	if code != 2 || !strings.Contains(errOut, "missing subcommand") || !strings.Contains(errOut, "usage: app db <subcommand>") {
+		t.Fatalf("a group without a handler should require a subcommand, got %q", errOut)
+	}
+	code, _, errOut = execute(&cli, "db", "migrat")
+	if code != 2 || !strings.Contains(errOut, "did you mean \"migrate\"?") {
+		t.Fatalf("unexpected error output %q", errOut)
+	}
+}
+
+func TestHelpTree(t *testing.T) {
+	cli, _ := sampleTreeCli(t)
+	_, out, _ := execute(&cli, "help", "db")
+	if !strings.Contains(out, "SUBCOMMANDS\n  migrate\tRun migrations.") {
+		t.Fatalf("group help should list its children, got %q", out)
+	}
+	for _, args := range [][]string{{"help", "db", "migrate", "up"}, {"db", "migrate", "up", "--help"}} {
+		code, out, _ := execute(&cli, args...)
+		if code != 0 || !strings.Contains(out, "usage: app db migrate up [OPTIONS]") || !strings.Contains(out, "--steps") {
+			t.Fatalf("unexpected help output %q", out)
+		}
+	}
+	code, _, errOut := execute(&cli, "help", "db", "migrate", "down")
+	if code != 2 || !strings.Contains(errOut, "unknown subcommand \"down\"") {
+		t.Fatalf("unexpected error output %q", errOut)
+	}
+}
+
+func TestHelpFlagInOptions(t *testing.T) {
+	cli := sampleCli(t)
+	cp := samplePositionalHandler(t)
+	cp.Handle(func(h *SubcommandHandler) {})
+	cli.HandleSubcommand(cp)
+	for _, args := range [][]string{
+		{"greet", "--name", "x", "--help"},
+		{"greet", "--times", "2", "-h", "--name", "x"},
+		{"cp", "a", "b", "--help"},
+	} {
+		code, out, errOut := execute(&cli, args...)
+		if code != 0 || !strings.Contains(out, "usage: app "+args[0]) || errOut != "" {
+			t.Fatalf("%v: expected help, got %d %q %q", args, code, out, errOut)
+		}
+	}
+	code, _, errOut := execute(&cli, "cp", "a", "1", "--", "--help")
+	if code != 0 || errOut != "" {
+		t.Fatalf("--help after -- should be an operand, got %d %q", code, errOut)
+	}
+	cp.StopAtFirstNonOption(true)
+	code, out, _ := execute(&cli, "cp", "a", "1", "--help")
+	if code != 0 || out != "" {
+		t.Fatalf("--help after the first operand should be an operand, got %d %q", code, out)
+	}
+}
+
+func TestMissingValueIsReportedOnce(t *testing.T) {
+	cli := sampleCli(t)
+	code, _, errOut := execute(&cli, "greet", "--name")
+	if code != 2 || !strings.Contains(errOut, "error: missing value for --name, expected str\n") || strings.Contains(errOut, "missing required argument") {
+		t.Fatalf("expected one error for --name, got %q", errOut)
+	}
+	_, _, errOut = execute(&cli, "greet", "--times")
+	if !strings.Contains(errOut, "missing value for --times") || !strings.Contains(errOut, "missing required argument --name") {
+		t.Fatalf("other required arguments should still be reported, got %q", errOut)
+	}
+}
@@ -0,0 +1,179 @@
+package goldcmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleClusterCli(t *testing.T) Cli {
+	cli := NewCli("1.0", "A CLI with short flags.")
+	sub, _ := NewSubcommandHandler("tar", "Work with archives.")
+	sub.AddBoolParamWithDefault([]string{"x", "extract"}, "extract files", false)
+	sub.AddBoolParamWithDefault([]string{"z", "gzip"}, "filter through gzip", false)
+	sub.AddBoolParamWithDefault([]string{"v", "verbose"}, "list the files", false)
+	sub.AddStrParamWithDefault([]string{"f", "file"}, "the archive", "-")
+	sub.AddIntParamWithDefault([]string{"n", "level"}, "the compression level", 6)
+	sub.AddStrsArg([]string{"exclude", "e"}, "a pattern to exclude")
+	sub.ClusterShortFlags(true)
+	sub.Handle(func(h *SubcommandHandler) {
+		x, _ := h.GetBool("x")
+		z, _ := h.GetBool("z")
+		v, _ := h.GetBool("v")
+		f, _ := h.GetStr("f")
+		n, _ := h.GetInt("n")
+		e, _ := h.GetStrs("e")
+		fmt.Fprintf(h.Stdout(), "%t %t %t %s %d %v", x, z, v, f, n, e)
+	})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func TestClusterShortFlags(t *testing.T) {
+	cli := sampleClusterCli(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"tar", "-xzvf", "a.tgz", "-e", "*.o"}, "true true true a.tgz 6 [*.o]"},
+		{[]string{"tar", "-xfa.tgz", "-n5", "-e*.o"}, "true false false a.tgz 5 [*.o]"},
+		{[]string{"tar", "-vn=9", "-e", "-x"}, "false false true - 9 [-x]"},
+		{[]string{"tar", "--extract", "--level", "1", "--exclude=a", "--e", "b"}, "true false false - 1 [a b]"},
+		{[]string{"tar", "-e", "a", "-v=false", "-z"}, "false true false - 6 [a]"},
+	}
+	for _, test := range tests {
+		if code, out, errOut := execute(&cli, test.args...); code != 0 || out != test.want {
+			t.Fatalf("%v: expected %q, got %d %q %q", test.args, test.want, code, out, errOut)
+		}
+	}
+}
+
+func TestClusterShortFlagErrors(t *testing.T) {
+	cli := sampleClusterCli(t)
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"tar", "-e", "a", "-level"}, "error: unknown flag -l, did you mean -e?"},
+		{[]string{"tar", "-e", "a", "-xq"}, "error: unknown flag -q"},
+		{[]string{"tar", "-e", "a", "-nx"}, `error: invalid value "x" for -n, expected int`},
+		{[]string{"tar", "-e", "a", "-n", "abc"}, `error: invalid value "abc" for -n, expected int`},
+		{[]string{"tar", "-e", "a", "-vf"}, "error: missing value for -f, expected str"},
+		{[]string{"tar", "-e", "a", "--lvel", "1"}, "error: unknown flag --lvel, did you mean --level?"},
+		{[]string{"tar", "-x"}, "error: missing required argument --exclude"},
+	}
+	for _, test := range tests {
+		if code, _, errOut := execute(&cli, test.args...); code != 2 || !strings.Contains(errOut, test.err) {
+			t.Fatalf("%v: expected %q, got %d %q", test.args, test.err, code, errOut)
+		}
+	}
+}
+
+func TestClusterShortFlagHelp(t *testing.T) {
+	cli := sampleClusterCli(t)
+	_, out, _ := execute(&cli, "help", "tar")
+	for _, want := range []string{
+		"usage: app tar --exclude <str>... [OPTIONS]\n",
+		" --exclude, -e <str>\ta pattern to exclude [repeatable]\n",
+		" -x, --extract\textract files\n",
+		" -n, --level <int>\tthe compression level\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+	var b bytes.Buffer
+	cli.complete(&b, []string{"tar", "-"})
+	if !strings.Contains(b.String(), "-x\textract files\n--extract\textract files\n") {
+		t.Fatalf("expected short flags in the completion, got %q", b.String())
+	}
+	spec := cli.Spec()
+	b.Reset()
+	writeJSON(&b, spec)
+	loaded, err := NewCliFromJSON(b.Bytes(), Handlers{"tar": func(h *SubcommandHandler) {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !spec.Subcommands[0].ClusterShortFlags || !reflect.DeepEqual(loaded.Spec(), spec) {
+		t.Fatalf("the loaded CLI differs from the original:\n%+v\n%+v", loaded.Spec(), spec)
+	}
+}
+
+func TestClusterShortFlagExamples(t *testing.T) {
+	cli := sampleClusterCli(t)
+	tar := cli.subcommands[0]
+	tar.Example("Extract an archive.", "app tar -xzvfa.tgz -e '*.o'", "")
+	tar.Example("List an archive.", "app tar -vn9 -e a -f a.tgz", "")
+	if err := cli.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tar.Example("Extract quietly.", "app tar -xqf a.tgz -e a", "")
+	if err := cli.Validate(); err == nil || !strings.Contains(err.Error(), `uses the unknown flag -q`) {
+		t.Fatalf("expected an unknown flag, got %v", err)
+	}
+}
+
+func TestClusterShortFlagNames(t *testing.T) {
+	cli := sampleClusterCli(t)
+	tar := cli.subcommands[0]
+	for _, err := range []error{tar.MutuallyExclusive("x", "z"), tar.RequiredIf("n", WhenSet("v"))} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"tar", "-xz", "-e", "a"}, "error: -x and -z cannot be used together\n"},
+		{[]string{"tar", "-v", "-e", "a"}, "error: -n is required when -v is given\n"},
+	}
+	for _, test := range tests {
+		if code, _, errOut := execute(&cli, test.args...); code != 2 || !strings.Contains(errOut, test.err) {
+			t.Fatalf("%v: expected %q, got %d %q", test.args, test.err, code, errOut)
+		}
+	}
+	_, out, _ := execute(&cli, "help", "tar")
+	for _, want := range []string{"[-x | -z]", " -n, --level <int>\tthe compression level [required when -v is given]\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+	_, out, _ = execute(&cli, "tar", "-e", "a", "--show-config")
+	if !strings.Contains(out, "\n-x ") || !strings.Contains(out, "\n--exclude ") {
+		t.Fatalf("expected short flags in the sources, got %q", out)
+	}
+	cli.SetName("app")
+	if page := cli.ManPages()["app-tar.1"]; !strings.Contains(page, "\\fB\\-x\\fR, \\fB\\-\\-extract\\fR") {
+		t.Fatalf("expected short flags in the man page:\n%s", page)
+	}
+	if page := cli.MarkdownDocs()["app-tar.md"]; !strings.Contains(page, "`-x`, `--extract`") {
+		t.Fatalf("expected short flags in the docs:\n%s", page)
+	}
+}
+
+func TestClusterShortFlagCompletion(t *testing.T) {
+	cli := sampleClusterCli(t)
+	tar := cli.subcommands[0]
+	tar.AddChoiceParamWithDefault([]string{"c", "codec"}, "the codec", []string{"gzip", "xz"}, "gzip")
+	var b bytes.Buffer
+	cli.complete(&b, []string{"tar", "-xc", ""})
+	if want := "gzip\nxz\n:1\n"; b.String() != want {
+		t.Fatalf("expected %q, got %q", want, b.String())
+	}
+	b.Reset()
+	cli.complete(&b, []string{"tar", "-xcxz", ""})
+	if strings.Contains(b.String(), "gzip") {
+		t.Fatalf("the cluster holds its value, got %q", b.String())
+	}
+}
+
+func TestClusterShortFlagBadValue(t *testing.T) {
+	cli := sampleClusterCli(t)
+	code, _, errOut := execute(&cli, "tar", "-vn", "abc", "-e", "a")
+	if code != 2 || strings.Count(errOut, "error:") != 1 || !strings.Contains(errOut, `invalid value "abc"`) {
+		t.Fatalf("expected one error, got %d %q", code, errOut)
+	}
+}
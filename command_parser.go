@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // A struct to parse arguments
@@ -12,8 +14,7 @@ type commandParser struct {
 	allLabels []string
 
 	// Here, the command line options are called "labels".
-	// Labels are associated with one of the supported types.
-	// Each label can have a number of aliases.
+	// Each label has a value of some type, and can have a number of aliases.
 	//
 	// Example:
 	// $ some_command --a=some_string_argument -b 1
@@ -21,43 +22,70 @@ type commandParser struct {
 	// Note, as far as the library is concerned, this command is equivalent to
 	// $ some_command -a "some_string_argument" --b=1
 	//
-	// All of the aliases for a label are stored together in an array.
-	// The set of sets of aliases are then stored in an array, one for each supported type.
-	// This way, strong typing is enforced without need for reflection.
+	// The values implement the Value interface, so the parser does not need
+	// to know about their types, and the built-in types are no different
+	// from the ones defined by applications.
+	labels []*label
 
-	intLabels   [][]string
-	strLabels   [][]string
-	floatLabels [][]string
-	boolLabels  [][]string
+	// The separator each value of a list label is split on, if it has one,
+	// and the number of values it takes, by the first alias of the label.
+	separators map[string]string
+	counts     map[string]valueCount
 
-	// After parsing the values, all of the arguments are stored in a map from label aliases to
-	// the value set by the user.
-	// If a label has multiple aliases, all of them will be mapped to the value set by the user.
+	// The validators of the labels, or of the positional arguments for the
+	// positional parser, by the first alias of the label.
+	validators map[string][]Validator
 
-	intValues   map[string]int
-	strValues   map[string]string
-	floatValues map[string]float64
-	boolValues  map[string]bool
+	// The conditions under which the labels are required, by the first
+	// alias of the label.
+	conditions map[string][]Condition
 
-	// Documentation for the arguments. Keys are the documentation value, values
-	// are lists of labels associated with the argument.
-	menu map[string][]string
+	// The environment variables bound to the labels, by the first alias of
+	// the label. If envPrefix is set, every label is also bound to the
+	// variable named after the prefix and the label e.g. "APP_TIMEOUT".
+	env       map[string][]string
+	envPrefix string
+
+	// Where the values of the last parse come from, by the first alias of
+	// the label. A label without a source has no value.
+	sources map[string]Source
+
+	// If set, single character aliases are written with one dash e.g. "-v",
+	// as they are given when short flags are clustered.
+	shortFlags bool
+}
+
+// A label of a commandParser and its value.
+type label struct {
+	// all of the aliases of the label
+	aliases []string
+	// documentation for the label
+	documentation string
+	// the value, which every alias refers to
+	value Value
+	// if set, the value is restored to the default before each parse
+	hasDefault bool
+	// the default, formatted as it would be given on the command line, or
+	// as a list of values for a list label
+	deflt        string
+	defaultItems []string
+	// the underlying Go value of the default, or the formatted default if
+	// the value does not have one
+	defaultValue interface{}
 }
 
 // Create a new, but empty commandParser instance. Application code will set the
 // fill its state.
 func newCommandParser() *commandParser {
 	return &commandParser{
-		allLabels:   make([]string, 0),
-		intLabels:   make([][]string, 0),
-		strLabels:   make([][]string, 0),
-		floatLabels: make([][]string, 0),
-		boolLabels:  make([][]string, 0),
-		intValues:   make(map[string]int),
-		strValues:   make(map[string]string),
-		floatValues: make(map[string]float64),
-		boolValues:  make(map[string]bool),
-		menu:        make(map[string][]string),
+		allLabels:  make([]string, 0),
+		labels:     make([]*label, 0),
+		separators: make(map[string]string),
+		counts:     make(map[string]valueCount),
+		validators: make(map[string][]Validator),
+		conditions: make(map[string][]Condition),
+		env:        make(map[string][]string),
+		sources:    make(map[string]Source),
 	}
 }
 
@@ -71,70 +99,128 @@ func (cp *commandParser) hasAlias(newAlias string) bool {
 	return false
 }
 
-// Add a set of label aliases to a commandParser instance.
-// This should only be called by the add*Arg functions.
-func (cp *commandParser) addLabel(aliases []string, doc string, typeLabels *[][]string) {
+// Add a set of label aliases with a value to a commandParser instance. The
+// label does not have a value until it is set.
+// Warning, the caller should check the labels are not in use before calling this function.
+func (cp *commandParser) addLabel(aliases []string, doc string, value Value) {
 	cp.allLabels = append(cp.allLabels, aliases...)
-	*typeLabels = append(*typeLabels, aliases)
-	cp.menu[doc] = aliases
+	cp.labels = append(cp.labels, &label{aliases: aliases, documentation: doc, value: value})
+}
+
+// Add a set of label aliases with a value to a commandParser instance, where
+// the current value is the default.
+// Warning, the caller should check the labels are not in use before calling this function.
+func (cp *commandParser) addLabelWithDefault(aliases []string, doc string, value Value) {
+	cp.addLabel(aliases, doc, value)
+	l := cp.labels[len(cp.labels)-1]
+	if d, ok := value.(defaultKeeper); ok {
+		d.keepDefault()
+	}
+	l.hasDefault = true
+	l.deflt = value.String()
+	l.defaultValue = l.deflt
+	if list, ok := value.(listValue); ok {
+		l.defaultItems = append([]string{}, list.items()...)
+	}
+	if g, ok := value.(getter); ok {
+		l.defaultValue = g.get()
+	}
+	cp.sources[aliases[0]] = Source{Kind: SourceDefault}
 }
 
 // Add a label set for a new integer argument.
 // Warning, the caller should check the labels are not in use before calling this function.
 func (cp *commandParser) addIntArg(aliases []string, doc string) {
-	cp.addLabel(aliases, doc, &cp.intLabels)
+	cp.addLabel(aliases, doc, new(intValue))
 }
 
 // Add a label set for a new string argument.
 // Warning, the caller should check the labels are not in use before calling this function.
 func (cp *commandParser) addStrArg(aliases []string, doc string) {
-	cp.addLabel(aliases, doc, &cp.strLabels)
+	cp.addLabel(aliases, doc, new(strValue))
 }
 
 // Add a label set for a new float argument.
 // Warning, the caller should check the labels are not in use before calling this function.
 func (cp *commandParser) addFloatArg(aliases []string, doc string) {
-	cp.addLabel(aliases, doc, &cp.floatLabels)
+	cp.addLabel(aliases, doc, new(floatValue))
 }
 
 // Add a label set for a new Boolean argument.
 // Warning, the caller should check the labels are not in use before calling this function.
 func (cp *commandParser) addBoolArg(aliases []string, doc string) {
-	cp.addLabel(aliases, doc, &cp.boolLabels)
+	cp.addLabel(aliases, doc, new(boolValue))
 }
 
-// Set the value for an integer argument.
-func (cp *commandParser) setIntArg(aliases []string, value int) {
-	for _, alias := range aliases {
-		cp.intValues[alias] = value
+// Restore the value of a label to its default.
+func (l *label) reset() {
+	if r, ok := l.value.(resetter); ok {
+		r.Reset()
+	} else if list, ok := l.value.(listValue); ok {
+		list.setItems(l.defaultItems, false)
+	} else if l.hasDefault {
+		l.value.Set(l.deflt)
 	}
 }
 
-// Set the value for a string argument.
-func (cp *commandParser) setStrArg(aliases []string, value string) {
-	for _, alias := range aliases {
-		cp.strValues[alias] = value
+// Forget the values from a previous parse, keeping only the defaults.
+func (cp *commandParser) reset() {
+	cp.sources = make(map[string]Source)
+	for _, l := range cp.labels {
+		l.reset()
+		if l.hasDefault {
+			cp.sources[l.aliases[0]] = Source{Kind: SourceDefault}
+		}
 	}
 }
 
-// Set the value for a float argument.
-func (cp *commandParser) setFloatArg(aliases []string, value float64) {
-	for _, alias := range aliases {
-		cp.floatValues[alias] = value
-	}
+// Parse the command line flags. The variable "args" is the command line arguments.
+// The function returns a *ParseError for every value given for one of this
+// parser's labels that could not be used, and for every unknown label.
+func (cp *commandParser) parseFlags(args []string) ErrorList {
+	return scanFlags(args, []*commandParser{cp}, parseOptions{start: 2})
 }
 
-// Set the value for a Boolean argument.
-func (cp *commandParser) setBoolArg(aliases []string, value bool) {
-	for _, alias := range aliases {
-		cp.boolValues[alias] = value
+// Options that change how a command line is scanned.
+type parseOptions struct {
+	// the index of the first token after the command words
+	start int
+	// if set, unknown labels are collected instead of reported as errors
+	allowUnknown bool
+	// the collected unknown label tokens, if allowUnknown is set
+	unknown *[]string
+	// if not nil, the indexes of the tokens that are not flags or flag values
+	// are collected here, otherwise those tokens are skipped
+	operands *[]int
+	// if set, the first operand ends the options, as if it followed "--"
+	stopAtOperand bool
+	// if not nil, the index of the first token after the options end is
+	// stored here, or len(args) if the options do not end early
+	endOfOptions *int
+	// if not nil, the index of every unknown flag is added here, when unknown
+	// flags are allowed
+	unknownAt *[]int
+	// if set, a token with one dash is a cluster of single character labels
+	// e.g. '-xzvf', and only a token with two dashes is a long label
+	clusterShort bool
+}
+
+// Find the parser that uses a label alias, or nil if no parser uses it.
+func parserForAlias(alias string, parsers []*commandParser) *commandParser {
+	for _, cp := range parsers {
+		if cp.hasAlias(alias) {
+			return cp
+		}
 	}
+	return nil
 }
 
-// Parse the command line flags. The variable "args" is the command line arguments.
-func (cp *commandParser) parseFlags(args []string) {
-	// The first and second argument are the command invocation and the
-	// subcommand, so we can skip them. If the binary for the CLI is 'cli' and
+// Parse the command line flags for a set of parsers, where each label is
+// used by at most one of the parsers. Labels that no parser uses are errors,
+// unless the options allow them.
+func scanFlags(args []string, parsers []*commandParser, opts parseOptions) ErrorList {
+	// The tokens before opts.start are the command invocation and the
+	// subcommand words, so we can skip them. If the binary for the CLI is 'cli' and
 	// the subcommand is 'sub' with only one argument set by label 'label' and
 	// value 'val', then the following invocations are valid:
 	// - $ cli sub --label val
@@ -144,104 +230,449 @@ func (cp *commandParser) parseFlags(args []string) {
 	// If the argument type is Boolean, then argument can be implicitly set:
 	// - $ cli sub --label # implicit true
 	// If multiple labels set a value the last one is used. Fight me.
-	k := 2
+	// The exception is list labels, which keep every value given.
+	// The token "--" ends the options: every later token is an operand, even
+	// if it starts with '-'.
+	errs := make(ErrorList, 0)
+	if opts.endOfOptions != nil {
+		*opts.endOfOptions = len(args)
+	}
+	endOptions := func(index int) {
+		if opts.endOfOptions != nil {
+			*opts.endOfOptions = index
+		}
+		for ; index < len(args); index++ {
+			if opts.operands != nil {
+				*opts.operands = append(*opts.operands, index)
+			}
+		}
+	}
+	fail := func(index int, label string, cp *commandParser, err error) *ParseError {
+		token := ""
+		if index < len(args) {
+			token = args[index]
+		}
+		e := &ParseError{Args: args, Token: token, Index: index,
+			Label: label, Flag: cp.flagName(label), Type: cp.labelType(label), Err: err}
+		errs = append(errs, e)
+		return e
+	}
+	use := func(cp *commandParser, label string, value string, index int) error {
+		err := cp.tryToUseFlag(label, value)
+		if err == nil {
+			cp.sources[cp.aliasesOf(label)[0]] = Source{Kind: SourceCommandLine, Index: index}
+		}
+		return err
+	}
+	unknownFlag := func(index int, label string) {
+		if opts.allowUnknown {
+			if opts.unknown != nil {
+				*opts.unknown = append(*opts.unknown, args[index])
+			}
+			if opts.unknownAt != nil {
+				*opts.unknownAt = append(*opts.unknownAt, index)
+			}
+			return
+		}
+		known := make([]string, 0)
+		for _, p := range parsers {
+			known = append(known, p.allLabels...)
+		}
+		s := suggest(label, known)
+		errs = append(errs, &ParseError{Args: args, Token: args[index], Index: index, Label: label,
+			Flag: flagName(label, opts.clusterShort), Err: ErrUnknownFlag, Suggestion: s, suggestedFlag: flagName(s, opts.clusterShort)})
+	}
+	// Use a cluster of single character labels e.g. '-xzvf file' or '-n5',
+	// and get the number of tokens it takes. Boolean labels are set, and the
+	// first other label takes the rest of the token as its value, or the
+	// next token if the rest is empty.
+	cluster := func(index int) int {
+		chars := []rune(args[index][1:])
+		for i, c := range chars {
+			label := string(c)
+			cp := parserForAlias(label, parsers)
+			if cp == nil {
+				unknownFlag(index, label)
+				return 1
+			}
+			rest := string(chars[i+1:])
+			if cp.isBool(label) && !strings.HasPrefix(rest, "=") {
+				use(cp, label, "", index)
+				continue
+			}
+			if i+1 < len(chars) {
+				// e.g. '-n5' or '-n=5'
+				value := strings.TrimPrefix(rest, "=")
+				if err := use(cp, label, value, index); err != nil {
+					fail(index, label, cp, err).value = value
+				}
+				return 1
+			}
+			if index < len(args)-1 {
+				if err := use(cp, label, args[index+1], index); err != nil {
+					fail(index+1, label, cp, err)
+					if isFlagToken(args[index+1]) {
+						return 1
+					}
+				}
+				return 2
+			}
+			fail(index+1, label, cp, ErrMissingValue)
+			return 1
+		}
+		return 1
+	}
+	k := opts.start
 	for k < len(args) {
 		arg := args[k]
 		start := 0
-		for arg[start] == '-' && start < len(arg) {
+		for start < len(arg) && arg[start] == '-' {
 			start += 1
 		}
-		if start == 1 || start == 2 {
-			end := 0
-			for end < len(arg) && arg[end] != '=' {
-				end += 1
+		if arg == "--" {
+			endOptions(k + 1)
+			break
+		}
+		if opts.stopAtOperand && (start == 0 || start == len(arg)) {
+			endOptions(k)
+			break
+		}
+		if (start != 1 && start != 2) || start == len(arg) || (isNumber(arg) && parserForAlias(arg[start:], parsers) == nil) {
+			if opts.operands != nil {
+				*opts.operands = append(*opts.operands, k)
 			}
-			label := arg[start:end]
-			if end < len(arg)-1 {
-				cp.tryToUseFlag(label, arg[end+1:])
+			k++
+			continue
+		}
+		if opts.clusterShort && start == 1 {
+			k += cluster(k)
+			continue
+		}
+		end := start
+		for end < len(arg) && arg[end] != '=' {
+			end += 1
+		}
+		label := arg[start:end]
+		cp := parserForAlias(label, parsers)
+		if cp == nil {
+			unknownFlag(k, label)
+			k++
+			continue
+		}
+		if end < len(arg) {
+			if err := use(cp, label, arg[end+1:], k); err != nil {
+				fail(k, label, cp, err)
+			}
+			k++
+		} else if cp.isBool(label) {
+			// Boolean labels only take the next value if it is explicit.
+			if k < len(args)-1 && (args[k+1] == "true" || args[k+1] == "false") {
+				use(cp, label, args[k+1], k)
+				k += 2
+			} else {
+				use(cp, label, "", k)
 				k++
-			} else if k < len(args)-1 {
-				if cp.tryToUseFlag(label, args[k+1]) == nil {
-					k += 2
-				} else {
+			}
+		} else if k < len(args)-1 {
+			if err := use(cp, label, args[k+1], k); err == nil {
+				k += 2
+			} else {
+				// the bad value is not an operand, unless it is another flag
+				fail(k+1, label, cp, err)
+				if isFlagToken(args[k+1]) {
 					k++
+				} else {
+					k += 2
 				}
-			} else {
-				cp.tryToUseFlag(label, "")
-				k++
 			}
 		} else {
+			fail(k+1, label, cp, ErrMissingValue)
 			k++
 		}
 	}
+	return errs
+}
+
+// Return true if a token looks like a flag, or is the "--" terminator, rather
+// than a value e.g. '--force' but not '-' or '-5'.
+func isFlagToken(s string) bool {
+	return len(s) > 1 && strings.HasPrefix(s, "-") && !isNumber(s)
 }
 
-// Check if a string is in a (2D) list of strings. The function returns
-// the row in which the string can be found or -1 if it is not in the string.
-func strInStrList(s string, l [][]string) int {
-	for n, row := range l {
-		for _, elm := range row {
-			if s == elm {
-				return n
+// Return true if a token is a number, e.g. a negative number that should not
+// be mistaken for a label.
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// Get the label an alias belongs to, or nil if the alias is not in use.
+func (cp *commandParser) labelOf(alias string) *label {
+	for _, l := range cp.labels {
+		for _, a := range l.aliases {
+			if a == alias {
+				return l
 			}
 		}
 	}
-	return -1
+	return nil
 }
 
 // Try to use a possible flag and value. The function will return an error if the
 // label / value combination cannot be used by the command specification.
 func (cp *commandParser) tryToUseFlag(alias string, possibleValue string) error {
-	if row := strInStrList(alias, cp.intLabels); row >= 0 {
-		if val, err := strconv.Atoi(possibleValue); err == nil {
-			cp.setIntArg(cp.intLabels[row], val)
-			return nil
-		} else {
+	l := cp.labelOf(alias)
+	if l == nil {
+		return errors.New(fmt.Sprintf("The label \"%s\" was not found to be a supported type.", alias))
+	}
+	if _, ok := l.value.(listValue); ok {
+		return cp.useItems(alias, cp.splitValue(alias, possibleValue))
+	}
+	if possibleValue == "" && isBoolValue(l.value) {
+		possibleValue = "true"
+	}
+	return l.value.Set(possibleValue)
+}
+
+// Use a value from the environment or a configuration file for the label an
+// alias belongs to, where a Boolean must be spelled out, see explicitBool.
+func (cp *commandParser) useExplicit(alias string, value string) error {
+	if l := cp.labelOf(alias); l != nil {
+		var err error
+		if value, err = explicitBool(l.value, value); err != nil {
 			return err
 		}
 	}
+	return cp.tryToUseFlag(alias, value)
+}
 
-	if row := strInStrList(alias, cp.strLabels); row >= 0 {
-		cp.setStrArg(cp.strLabels[row], possibleValue)
-		return nil
+// Return true if the label an alias belongs to can be given without a value.
+func (cp *commandParser) isBool(alias string) bool {
+	if l := cp.labelOf(alias); l != nil {
+		return isBoolValue(l.value)
 	}
-	if row := strInStrList(alias, cp.floatLabels); row >= 0 {
-		if val, err := strconv.ParseFloat(possibleValue, 64); err == nil {
-			cp.setFloatArg(cp.floatLabels[row], val)
-			return nil
-		} else {
-			return err
-		}
+	return false
+}
+
+// Get the type name of a label alias, or an empty string if the alias is not
+// in use.
+func (cp *commandParser) labelType(alias string) string {
+	if l := cp.labelOf(alias); l != nil {
+		return l.value.Type()
 	}
+	return ""
+}
 
-	if row := strInStrList(alias, cp.boolLabels); row >= 0 {
-		if possibleValue == "true" {
-			cp.setBoolArg(cp.boolLabels[row], true)
-			return nil
-		} else if possibleValue == "false" {
-			cp.setBoolArg(cp.boolLabels[row], false)
-			return nil
-		} else {
-			cp.setBoolArg(cp.boolLabels[row], true)
-			return nil
+// Get all of the aliases of the label an alias belongs to.
+func (cp *commandParser) aliasesOf(alias string) []string {
+	if l := cp.labelOf(alias); l != nil {
+		return l.aliases
+	}
+	return nil
+}
+
+// Get the documentation of the label an alias belongs to.
+func (cp *commandParser) docOf(alias string) string {
+	if l := cp.labelOf(alias); l != nil {
+		return l.documentation
+	}
+	return ""
+}
+
+// Get the default value of the label an alias belongs to, formatted as it
+// would be given on the command line. The second return value is false if
+// the label has no default.
+func (cp *commandParser) defaultOf(alias string) (string, bool) {
+	l := cp.labelOf(alias)
+	if l == nil || !l.hasDefault {
+		return "", false
+	}
+	if _, ok := l.value.(listValue); ok {
+		return cp.joinValues(alias, l.defaultItems), true
+	}
+	return l.deflt, true
+}
+
+// Get the alias sets of every label, in the order they were added.
+func (cp *commandParser) labelRows() [][]string {
+	rows := make([][]string, 0, len(cp.labels))
+	for _, l := range cp.labels {
+		rows = append(rows, l.aliases)
+	}
+	return rows
+}
+
+// Return true if a value has been set for a label alias.
+func (cp *commandParser) isSet(alias string) bool {
+	l := cp.labelOf(alias)
+	return l != nil && cp.sources[l.aliases[0]].Kind != SourceUnset
+}
+
+// Get the underlying Go value of the label an alias belongs to e.g. an int.
+// The second return value is false if the label has no value, or if its
+// value is not one of the built-in types.
+func (cp *commandParser) get(alias string) (interface{}, bool) {
+	l := cp.labelOf(alias)
+	if l == nil || !cp.isSet(alias) {
+		return nil, false
+	}
+	g, ok := l.value.(getter)
+	if !ok {
+		return nil, false
+	}
+	return g.get(), true
+}
+
+// Get an error for every label that does not have a value. Labels named by a
+// *ParseError in `reported`, e.g. for a flag given without its value, are left
+// out, as that error already says what is wrong with them.
+func (cp *commandParser) checkAllSet(reported []error) []error {
+	named := make(map[*label]bool)
+	for _, err := range reported {
+		if pe, ok := err.(*ParseError); ok {
+			named[cp.labelOf(pe.Label)] = true
+		}
+	}
+	errs := make([]error, 0)
+	for _, row := range cp.labelRows() {
+		if !cp.isSet(row[0]) && !named[cp.labelOf(row[0])] {
+			errs = append(errs, fmt.Errorf("missing required argument %s", cp.flagName(row[0])))
 		}
 	}
+	return errs
+}
+
+// Get a usage string listing every label with its type e.g.
+// "--first <int> --second <int>".
+func (cp *commandParser) usageString() string {
+	parts := make([]string, 0)
+	for _, row := range cp.labelRows() {
+		parts = append(parts, cp.usageToken(row[0]))
+	}
+	return strings.Join(parts, " ")
+}
 
-	return errors.New(fmt.Sprintf("The label \"%s\" was not found to be a supported type.", alias))
+// Get an alias as it is written on the command line e.g. "--count", or "-c"
+// for a single character alias when short flags are clustered.
+func (cp *commandParser) flagName(alias string) string {
+	return flagName(alias, cp.shortFlags)
 }
 
-// Get the help string for a commandParser instance.
+// Get an alias as it is written on the command line, where `short` is set if
+// short flags are clustered.
+func flagName(alias string, short bool) string {
+	if short && utf8.RuneCountInString(alias) == 1 {
+		return "-" + alias
+	}
+	return "--" + alias
+}
+
+// Get the token for the label an alias belongs to in a usage line e.g.
+// "--count <int>", "--tag <str>..." or "--format {json,yaml}".
+func (cp *commandParser) usageToken(alias string) string {
+	name := cp.aliasesOf(alias)[0]
+	if strings.HasPrefix(cp.labelType(name), "[]") {
+		return cp.flagName(name) + " " + cp.valueToken(name) + "..."
+	}
+	return cp.flagName(name) + " " + cp.valueToken(name)
+}
+
+// Get the token for a value of the label an alias belongs to e.g. "<int>",
+// "<str>" for each value of a string list, or "{json,yaml}" for a choice.
+func (cp *commandParser) valueToken(alias string) string {
+	t := cp.labelType(alias)
+	if strings.HasPrefix(t, "{") {
+		return t
+	}
+	return "<" + strings.TrimPrefix(t, "[]") + ">"
+}
+
+// Get the help string for a commandParser instance, with the labels in the
+// order they were added.
 func (cp *commandParser) helpString() string {
 	var s string = ""
-	for doc, labels := range cp.menu {
+	for _, l := range cp.labels {
+		labels := l.aliases
+		doc := l.documentation
 		var ls string = ""
 		for k, label := range labels {
 			if k > 0 {
 				ls = ls + ","
 			}
-			ls = ls + " --" + label
+			ls = ls + " " + cp.flagName(label)
+		}
+		if !isBoolValue(l.value) {
+			ls = ls + " " + cp.valueToken(labels[0])
+		}
+		choices, _ := l.value.(*choiceValue)
+		if strings.HasPrefix(l.value.Type(), "[]") {
+			if sep, ok := cp.separators[labels[0]]; ok {
+				doc = doc + fmt.Sprintf(" [repeatable, split on %q]", sep)
+			} else {
+				doc = doc + " [repeatable]"
+			}
+		}
+		if rules := cp.rules(labels[0]); len(rules) > 0 {
+			doc = doc + " [" + strings.Join(rules, ", ") + "]"
+		}
+		for _, cond := range cp.conditions[labels[0]] {
+			doc = doc + " [required when " + cond.describe(cp) + "]"
+		}
+		if names := cp.envNames(labels[0]); len(names) > 0 {
+			doc = doc + " [env: " + strings.Join(names, ", ") + "]"
 		}
 		s = s + ls + "\t" + doc + "\n"
+		if choices != nil {
+			s = s + choices.helpString()
+		}
 	}
 	return s
 }
+
+// Get the name of the environment variable for a label under a prefix e.g.
+// "APP_DRY_RUN" for the label "dry-run" and the prefix "APP".
+func envName(prefix string, label string) string {
+	return prefix + "_" + strings.ToUpper(strings.ReplaceAll(label, "-", "_"))
+}
+
+// Get the environment variables bound to the label an alias belongs to, in
+// the order they are looked up.
+func (cp *commandParser) envNames(alias string) []string {
+	row := cp.aliasesOf(alias)
+	if len(row) == 0 {
+		return nil
+	}
+	names := append([]string{}, cp.env[row[0]]...)
+	if cp.envPrefix != "" {
+		if auto := envName(cp.envPrefix, row[0]); !strInList(auto, names) {
+			names = append(names, auto)
+		}
+	}
+	return names
+}
+
+// Set the labels that were not given on the command line from the
+// environment variables bound to them, using the first variable that is set
+// and not empty. The function `lookup` looks up a variable, like
+// os.LookupEnv. An error is returned for every value that cannot be used.
+func (cp *commandParser) parseEnv(lookup func(string) (string, bool)) []error {
+	errs := make([]error, 0)
+	for _, row := range cp.labelRows() {
+		if cp.sources[row[0]].Kind == SourceCommandLine {
+			continue
+		}
+		for _, name := range cp.envNames(row[0]) {
+			value, ok := lookup(name)
+			if !ok || value == "" {
+				continue
+			}
+			if err := cp.useExplicit(row[0], value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value %q for %s from $%s, expected %s: %s", value, cp.flagName(row[0]), name, cp.labelType(row[0]), unwrapNumError(err)))
+			} else {
+				cp.sources[row[0]] = Source{Kind: SourceEnv, Env: name}
+			}
+			break
+		}
+	}
+	return errs
+}
@@ -10,14 +10,11 @@ func TestNewCommandParser(t *testing.T) {
 	if len(cp.allLabels) != 0 {
 		t.Fatalf("new parser should not have labels")
 	}
-	if len(cp.intLabels) != 0 {
-		t.Fatalf("new parser should not have int labels declared")
+	if len(cp.labels) != 0 {
+		t.Fatalf("new parser should not have labels declared")
 	}
-	if len(cp.intValues) != 0 {
-		t.Fatalf("new parser should not have int values set")
-	}
-	if len(cp.menu) != 0 {
-		t.Fatalf("new parser should not have menu elements set")
+	if len(cp.sources) != 0 {
+		t.Fatalf("new parser should not have values set")
 	}
 }
 
@@ -55,35 +52,26 @@ func TestAddArgs(t *testing.T) {
 
 func TestSetArgs(t *testing.T) {
 	cp := sampleCommandParser()
-	cp.setIntArg([]string{"a", "b"}, 1337)
-	i1, ok1 := cp.intValues["a"]
-	i2, ok2 := cp.intValues["b"]
+	cp.parseFlags([]string{"cli", "sub", "-a", "1337", "-c", "hello world", "-e", "0.42", "-h"})
+	i1, ok1 := cp.get("a")
+	i2, ok2 := cp.get("b")
 	if !ok1 || !ok2 || i1 != 1337 || i2 != 1337 {
 		t.Fatalf("int value should be 1337 for aliases \"a\" and \"b\"")
 	}
-	cp.setStrArg([]string{"c"}, "hello world")
-	s, ok := cp.strValues["c"]
+	s, ok := cp.get("c")
 	if !ok || s != "hello world" {
 		t.Fatalf("string value should be \"hello world\" for alias \"c\"")
 	}
-	cp.setFloatArg([]string{"d", "e", "f"}, 0.42)
-	f, ok := cp.floatValues["d"]
-	if !ok || math.Abs(0.42-f) > 0.001 {
-		t.Fatalf("float value should be 0.42 for alias \"d\", f=%b, ok=%t", f, ok)
-	}
-	f, ok = cp.floatValues["e"]
-	if !ok || math.Abs(0.42-f) > 0.001 {
-		t.Fatalf("float value should be 0.42 for alias \"e\"")
-	}
-	f, ok = cp.floatValues["f"]
-	if !ok || math.Abs(0.42-f) > 0.001 {
-		t.Fatalf("float value should be 0.42 for alias \"f\"")
+	for _, alias := range []string{"d", "e", "f"} {
+		f, ok := cp.get(alias)
+		if !ok || math.Abs(0.42-f.(float64)) > 0.001 {
+			t.Fatalf("float value should be 0.42 for alias \"%s\", f=%v, ok=%t", alias, f, ok)
+		}
 	}
-	cp.setBoolArg([]string{"g", "h"}, true)
-	b1, ok1 := cp.boolValues["g"]
-	b2, ok2 := cp.boolValues["h"]
-	if !ok1 || !ok2 || !b1 || !b2 {
-		t.Fatalf("bool value should be true for aliases \"g\" and \"f\"")
+	b1, ok1 := cp.get("g")
+	b2, ok2 := cp.get("h")
+	if !ok1 || !ok2 || b1 != true || b2 != true {
+		t.Fatalf("bool value should be true for aliases \"g\" and \"h\"")
 	}
 }
 
@@ -133,14 +121,41 @@ func TestBasicParseFlags(t *testing.T) {
 		cp := sampleCommandParser()
 		cp.addIntArg([]string{"x"}, "another int arg")
 		cp.parseFlags(args)
-		i1, ok1 := cp.intValues["a"]
-		i2, ok2 := cp.intValues["b"]
+		i1, ok1 := cp.get("a")
+		i2, ok2 := cp.get("b")
 		if !ok1 || !ok2 || i1 != 1337 || i2 != 1337 {
 			t.Fatalf("int value should be 1337 for aliases \"a\" and \"b\"")
 		}
-		i, ok := cp.intValues["x"]
+		i, ok := cp.get("x")
 		if !ok || i != 42 {
 			t.Fatalf("int value should be 42 for alias \"x\"")
 		}
 	}
 }
+
+func TestParseFlagsErrors(t *testing.T) {
+	cp := sampleCommandParser()
+	errs := cp.parseFlags([]string{"cli", "sub", "-a=x", "-d", "y", "-c"})
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	if cp.isSet("a") || cp.isSet("d") || cp.isSet("c") {
+		t.Fatalf("labels with invalid values should not be set")
+	}
+	missing := cp.checkAllSet(nil)
+	if len(missing) != 4 {
+		t.Fatalf("expected 4 missing labels, got %v", missing)
+	}
+}
+
+func TestBoolDoesNotConsumeNextFlag(t *testing.T) {
+	cp := sampleCommandParser()
+	cp.parseFlags([]string{"cli", "sub", "-g", "-a", "3", "-h", "false"})
+	// "h" is an alias of "g", so the last value is used
+	if b, ok := cp.get("g"); !ok || b != false {
+		t.Fatalf("bool value should be false after \"-h false\"")
+	}
+	if i, ok := cp.get("a"); !ok || i != 3 {
+		t.Fatalf("int value should be 3 for alias \"a\"")
+	}
+}
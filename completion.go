@@ -0,0 +1,457 @@
+package goldcmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// A candidate for the word being completed by the shell.
+type Completion struct {
+	// the candidate value e.g. "production"
+	Value string
+	// a description shown next to the value by shells that support it
+	Description string
+}
+
+// A CompletionDirective tells the shell what to offer besides the candidates.
+type CompletionDirective int
+
+const (
+	// Offer the candidates, or file names if there are no candidates.
+	CompleteDefault CompletionDirective = iota
+	// Offer the candidates only.
+	CompleteNoFiles
+	// Offer file names. Any candidates are file extensions to filter by
+	// e.g. "json".
+	CompleteFiles
+	// Offer directory names.
+	CompleteDirs
+)
+
+// A CompletionFunc gets the candidates for the value of an argument,
+// parameter or positional argument at tab-time. The argument `toComplete` is
+// the part of the value typed so far. The flags given before the value have
+// already been parsed into `h`, as far as they could be.
+type CompletionFunc func(h *SubcommandHandler, toComplete string) ([]Completion, CompletionDirective)
+
+// Get a CompletionFunc offering a fixed list of values.
+func CompleteValues(values ...string) CompletionFunc {
+	candidates := make([]Completion, 0, len(values))
+	for _, value := range values {
+		candidates = append(candidates, Completion{Value: value})
+	}
+	return CompleteCandidates(candidates...)
+}
+
+// Get a CompletionFunc offering a fixed list of values with descriptions.
+func CompleteCandidates(candidates ...Completion) CompletionFunc {
+	return func(h *SubcommandHandler, toComplete string) ([]Completion, CompletionDirective) {
+		return candidates, CompleteNoFiles
+	}
+}
+
+// Get a CompletionFunc offering file names, optionally only those with one
+// of the given extensions e.g. "yaml".
+func CompleteFileNames(extensions ...string) CompletionFunc {
+	return func(h *SubcommandHandler, toComplete string) ([]Completion, CompletionDirective) {
+		candidates := make([]Completion, 0, len(extensions))
+		for _, ext := range extensions {
+			candidates = append(candidates, Completion{Value: strings.TrimPrefix(ext, ".")})
+		}
+		return candidates, CompleteFiles
+	}
+}
+
+// Get a CompletionFunc offering directory names.
+func CompleteDirectoryNames() CompletionFunc {
+	return func(h *SubcommandHandler, toComplete string) ([]Completion, CompletionDirective) {
+		return nil, CompleteDirs
+	}
+}
+
+// Set the function completing the value of an argument, parameter or
+// positional argument, where `label` is any of its aliases or its name.
+// Without a completion function, Boolean values complete to "true" and
+// "false", choice values complete to their choices, string values complete
+// to file names, and numbers do not complete.
+func (h *SubcommandHandler) SetCompletion(label string, f CompletionFunc) error {
+	if parserForAlias(label, h.parsers()) == nil && h.positional(label) == nil {
+		return fmt.Errorf("unknown label \"%s\"", label)
+	}
+	aliases := []string{label}
+	if cp := parserForAlias(label, h.parsers()); cp != nil {
+		aliases = cp.aliasesOf(label)
+	}
+	for _, alias := range aliases {
+		h.completers[alias] = f
+	}
+	return nil
+}
+
+// Get a positional argument by name, or nil if there is no such argument.
+func (h *SubcommandHandler) positional(name string) *positional {
+	for _, p := range h.positionals {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Get the candidates for the value of a label, using its completion function
+// if it has one.
+func (h *SubcommandHandler) valueCandidates(label string, typeName string, toComplete string) ([]Completion, CompletionDirective) {
+	if f, ok := h.completers[label]; ok {
+		return f(h, toComplete)
+	}
+	if choices := h.choiceOf(label); choices != nil {
+		return choices.candidates(), CompleteNoFiles
+	}
+	switch typeName {
+	case "bool":
+		return []Completion{{Value: "true"}, {Value: "false"}}, CompleteNoFiles
+	case "str", "[]str":
+		return nil, CompleteDefault
+	}
+	return nil, CompleteNoFiles
+}
+
+// Get the candidates for a flag of the subcommand.
+func (h *SubcommandHandler) flagCandidates() []Completion {
+	candidates := make([]Completion, 0)
+	for _, cp := range h.parsers() {
+		for _, row := range cp.labelRows() {
+			for _, alias := range row {
+				candidates = append(candidates, Completion{Value: cp.flagName(alias), Description: cp.docOf(row[0])})
+			}
+		}
+	}
+	return append(candidates, Completion{Value: "--help", Description: "print help"})
+}
+
+// Get the candidates for the visible subcommands of a list.
+func subcommandCandidates(subcmds []*SubcommandHandler) []Completion {
+	candidates := make([]Completion, 0, len(subcmds))
+	for _, subcmd := range visible(subcmds) {
+		candidates = append(candidates, Completion{Value: subcmd.name, Description: subcmd.documentation})
+	}
+	return candidates
+}
+
+// Get the completion candidates for the word `toComplete`, which follows the
+// command line words `preceding` (not including the program name).
+func (cli *Cli) completionCandidates(preceding []string, toComplete string) ([]Completion, CompletionDirective) {
+	args := append([]string{""}, preceding...)
+	if len(preceding) > 0 && isHelpToken(preceding[0]) {
+		// e.g. 'app help db <TAB>' completes the children of 'db'
+		subcmd, k := cli.resolve(args, 2)
+		if k < len(args) {
+			return nil, CompleteNoFiles
+		}
+		if subcmd == nil {
+			return subcommandCandidates(cli.allSubcommands()), CompleteNoFiles
+		}
+		return subcommandCandidates(subcmd.subcommands), CompleteNoFiles
+	}
+	subcmd, k := cli.resolve(args, 1)
+	if subcmd == nil {
+		if k < len(args) {
+			return nil, CompleteNoFiles
+		}
+		if strings.HasPrefix(toComplete, "-") {
+			return []Completion{{Value: "--help", Description: "print help"},
+				{Value: "--version", Description: "print the version of this app"}}, CompleteNoFiles
+		}
+		candidates := subcommandCandidates(cli.allSubcommands())
+		return append(candidates, Completion{Value: "help", Description: "this help message"}), CompleteNoFiles
+	}
+
+	// Parse what has been typed so far, so that completion functions can
+	// use the values of earlier flags, and find where the word is.
+	subcmd.parseFlags(args, k, true)
+	operands := make([]int, 0)
+	end := len(args)
+	scanFlags(args, subcmd.parsers(), parseOptions{start: k, allowUnknown: true, operands: &operands,
+		stopAtOperand: subcmd.stopAtFirstNonOption, endOfOptions: &end, clusterShort: subcmd.clusterShortFlags})
+	optionsEnded := end < len(args) || (subcmd.stopAtFirstNonOption && len(operands) > 0)
+
+	if !optionsEnded && len(args) > k {
+		// the word may be the value of the last flag e.g. '--env <TAB>'
+		last := args[len(args)-1]
+		if strings.HasPrefix(last, "-") && !strings.Contains(last, "=") {
+			label := strings.TrimLeft(last, "-")
+			if subcmd.clusterShortFlags && !strings.HasPrefix(last, "--") {
+				// e.g. '-xf <TAB>'
+				label = subcmd.clusterValueLabel(last)
+			}
+			if cp := parserForAlias(label, subcmd.parsers()); label != "" && cp != nil && !cp.isBool(label) {
+				return subcmd.valueCandidates(label, cp.labelType(label), toComplete)
+			}
+		}
+	}
+	if !optionsEnded && strings.HasPrefix(toComplete, "-") {
+		if i := strings.IndexByte(toComplete, '='); i >= 0 {
+			// e.g. '--env=pr<TAB>'
+			label := strings.TrimLeft(toComplete[:i], "-")
+			cp := parserForAlias(label, subcmd.parsers())
+			if cp == nil {
+				return nil, CompleteNoFiles
+			}
+			values, directive := subcmd.valueCandidates(label, cp.labelType(label), toComplete[i+1:])
+			if directive == CompleteFiles {
+				return values, directive
+			}
+			candidates := make([]Completion, 0, len(values))
+			for _, c := range values {
+				candidates = append(candidates, Completion{Value: toComplete[:i+1] + c.Value, Description: c.Description})
+			}
+			return candidates, directive
+		}
+		return subcmd.flagCandidates(), CompleteNoFiles
+	}
+
+	// the word is a subcommand name or a positional value
+	candidates := make([]Completion, 0)
+	if len(operands) == 0 && !optionsEnded {
+		candidates = append(candidates, subcommandCandidates(subcmd.subcommands)...)
+	}
+	var p *positional
+	if len(operands) < len(subcmd.positionals) {
+		p = subcmd.positionals[len(operands)]
+	} else if n := len(subcmd.positionals); n > 0 && subcmd.positionals[n-1].variadic {
+		p = subcmd.positionals[n-1]
+	}
+	if p == nil {
+		return candidates, CompleteNoFiles
+	}
+	values, directive := subcmd.valueCandidates(p.name, p.typeName, toComplete)
+	return append(candidates, values...), directive
+}
+
+// Get the flag of a cluster of short flags that takes the next word as its
+// value e.g. "f" for '-xf', or an empty string if the cluster does not end
+// with such a flag e.g. '-xfa.tar'.
+func (h *SubcommandHandler) clusterValueLabel(token string) string {
+	chars := []rune(token[1:])
+	for i, c := range chars {
+		label := string(c)
+		cp := parserForAlias(label, h.parsers())
+		if cp == nil {
+			return ""
+		}
+		if !cp.isBool(label) {
+			if i == len(chars)-1 {
+				return label
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// Run the hidden completion protocol used by the shell scripts. The words
+// are the command line after the program name up to the cursor, where the
+// last word is the one being completed (and may be empty). Each candidate is
+// printed on its own line as the value, a tab, and the description, and the
+// last line is a colon followed by the CompletionDirective, e.g.
+//
+//	production	the production cluster
+//	staging	the staging cluster
+//	:1
+func (cli *Cli) complete(w io.Writer, words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	toComplete := words[len(words)-1]
+	candidates, directive := cli.completionCandidates(words[:len(words)-1], toComplete)
+	for _, c := range candidates {
+		if directive != CompleteFiles && !strings.HasPrefix(c.Value, toComplete) {
+			continue
+		}
+		doc := strings.Join(strings.Fields(c.Description), " ")
+		if doc != "" {
+			fmt.Fprintf(w, "%s\t%s\n", c.Value, doc)
+		} else {
+			fmt.Fprintf(w, "%s\n", c.Value)
+		}
+	}
+	fmt.Fprintf(w, ":%d\n", directive)
+}
+
+// Write a shell completion script for the CLI to `w`, where `shell` is one of
+// "bash", "zsh" or "fish" and `prog` is the name of the program to complete.
+// The scripts ask the program for candidates at tab-time, using the hidden
+// '__complete' subcommand.
+func (cli *Cli) writeCompletionScript(w io.Writer, shell string, prog string) error {
+	fn := "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(prog, "_") + "_completion"
+	script := ""
+	switch shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell \"%s\", expected bash, zsh or fish", shell)
+	}
+	script = strings.ReplaceAll(script, "PROG", prog)
+	script = strings.ReplaceAll(script, "FUNC", fn)
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// The bash completion script, where PROG is the program name and FUNC is the
+// completion function name.
+//
+// The words are split from the line rather than taken from COMP_WORDS, which
+// bash splits at '='. Since bash still replaces only its own current word,
+// the part of our word before it is removed from the candidates.
+const bashCompletion = `# bash completion for PROG
+# Load it with: source <(PROG completion bash)
+FUNC() {
+    local line cur prefix directive value ext
+    local -a words lines candidates
+    line="${COMP_LINE:0:COMP_POINT}"
+    read -ra words <<< "$line"
+    if [[ "$line" =~ [[:space:]]$ ]]; then
+        words+=("")
+    fi
+    cur="${words[${#words[@]}-1]}"
+    prefix="${cur%"${COMP_WORDS[COMP_CWORD]}"}"
+    mapfile -t lines < <("${words[0]}" __complete "${words[@]:1}" 2>/dev/null)
+    if [[ ${#lines[@]} -eq 0 ]]; then
+        return
+    fi
+    directive="${lines[${#lines[@]}-1]#:}"
+    unset 'lines[${#lines[@]}-1]'
+    for value in "${lines[@]}"; do
+        candidates+=("${value%%$'\t'*}")
+    done
+    COMPREPLY=()
+    case "$directive" in
+        2)
+            if [[ ${#candidates[@]} -eq 0 ]]; then
+                mapfile -t COMPREPLY < <(compgen -f -- "$cur")
+            else
+                mapfile -t COMPREPLY < <(compgen -d -- "$cur")
+                for ext in "${candidates[@]}"; do
+                    mapfile -t -O "${#COMPREPLY[@]}" COMPREPLY < <(compgen -f -X "!*.${ext}" -- "$cur")
+                done
+            fi
+            compopt -o filenames 2>/dev/null
+            ;;
+        3)
+            mapfile -t COMPREPLY < <(compgen -d -- "$cur")
+            compopt -o filenames 2>/dev/null
+            ;;
+        *)
+            for value in "${candidates[@]}"; do
+                COMPREPLY+=("${value#"$prefix"}")
+            done
+            if [[ ${#COMPREPLY[@]} -eq 0 && "$directive" == 0 ]]; then
+                mapfile -t COMPREPLY < <(compgen -f -- "$cur")
+                compopt -o filenames 2>/dev/null
+            fi
+            ;;
+    esac
+}
+complete -F FUNC PROG
+`
+
+// The zsh completion script, where PROG is the program name and FUNC is the
+// completion function name.
+const zshCompletion = `#compdef PROG
+# zsh completion for PROG
+# Load it with: source <(PROG completion zsh)
+FUNC() {
+    local directive line value desc ext
+    local -a lines candidates
+    lines=("${(@f)$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    directive="${lines[-1]#:}"
+    lines=("${(@)lines[1,-2]}")
+    for line in "${lines[@]}"; do
+        [[ -z "$line" ]] && continue
+        value="${line%%$'\t'*}"
+        value="${value//:/\\:}"
+        desc=""
+        [[ "$line" == *$'\t'* ]] && desc="${line#*$'\t'}"
+        if [[ -n "$desc" ]]; then
+            candidates+=("${value}:${desc}")
+        else
+            candidates+=("${value}")
+        fi
+    done
+    case "$directive" in
+        2)
+            if (( ${#candidates} )); then
+                for ext in "${candidates[@]}"; do
+                    _files -g "*.${ext%%:*}"
+                done
+            else
+                _files
+            fi
+            ;;
+        3)
+            _files -/
+            ;;
+        *)
+            if (( ${#candidates} )); then
+                _describe 'values' candidates
+            elif [[ "$directive" == 0 ]]; then
+                _files
+            fi
+            ;;
+    esac
+}
+compdef FUNC PROG
+`
+
+// The fish completion script, where PROG is the program name and FUNC is the
+// completion function name.
+const fishCompletion = `# fish completion for PROG
+# Load it with: PROG completion fish | source
+function FUNC
+    set -l args (commandline -opc)
+    set -l prog $args[1]
+    set -e args[1]
+    set -l cur (commandline -ct)
+    set -l lines ($prog __complete $args "$cur" 2>/dev/null)
+    test (count $lines) -gt 0; or return
+    set -l directive (string replace ':' '' -- $lines[-1])
+    set -e lines[-1]
+    switch $directive
+        case 2
+            __fish_complete_path "$cur"
+        case 3
+            __fish_complete_directories "$cur"
+        case '*'
+            for line in $lines
+                echo $line
+            end
+            if test (count $lines) -eq 0 -a "$directive" = 0
+                __fish_complete_path "$cur"
+            end
+    end
+end
+complete -c PROG -f -a '(FUNC)'
+`
+
+// Get the built-in 'completion' subcommand.
+func (cli *Cli) completionSubcommand() *SubcommandHandler {
+	h, _ := NewSubcommandHandler("completion", "print a shell completion script")
+	h.AddStrPositional("shell", "the shell to complete for: bash, zsh or fish")
+	h.SetCompletion("shell", CompleteValues("bash", "zsh", "fish"))
+	h.examples = append(h.examples, subcommandExample{
+		documentation: "enable completion in the current bash session", command: "source <(%s completion bash)", withProg: true})
+	h.Handle(func(h *SubcommandHandler) {
+		shell, _ := h.GetStr("shell")
+		if err := cli.writeCompletionScript(h.Stdout(), shell, h.path[0]); err != nil {
+			fmt.Fprintf(h.Stderr(), "error: %s\n", err)
+			h.SetExitCode(2)
+		}
+	})
+	return h
+}
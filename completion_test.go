@@ -0,0 +1,132 @@
+package goldcmd
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func sampleCompletionCli(t *testing.T) Cli {
+	cli, _ := sampleTreeCli(t)
+	deploy, _ := NewSubcommandHandler("deploy", "Deploy the app.")
+	deploy.AddStrParamWithDefault([]string{"env", "e"}, "the environment to deploy to", "staging")
+	deploy.AddStrParamWithDefault([]string{"config"}, "a configuration file", "")
+	deploy.AddBoolParamWithDefault([]string{"dry-run"}, "only print what would happen", false)
+	deploy.AddStrPositional("dir", "the directory to deploy")
+	deploy.SetCompletion("env", CompleteCandidates(
+		Completion{Value: "production", Description: "the production cluster"},
+		Completion{Value: "staging", Description: "the staging cluster"}))
+	deploy.SetCompletion("config", CompleteFileNames("yaml", "json"))
+	deploy.SetCompletion("dir", CompleteDirectoryNames())
+	cli.HandleSubcommand(deploy)
+	return cli
+}
+
+func TestCompleteProtocol(t *testing.T) {
+	cli := sampleCompletionCli(t)
+	for _, c := range []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"d"}, "db\tManage the database.\ndeploy\tDeploy the app.\n:1\n"},
+		{[]string{"db", "migrate", ""}, "up\tApply migrations.\n:1\n"},
+		{[]string{"help", "db", ""}, "migrate\tRun migrations.\n:1\n"},
+		{[]string{"deploy", "--d"}, "--dry-run\tonly print what would happen\n:1\n"},
+		{[]string{"deploy", "--env", "p"}, "production\tthe production cluster\n:1\n"},
+		{[]string{"deploy", "-e=s"}, "-e=staging\tthe staging cluster\n:1\n"},
+		{[]string{"deploy", "--dry-run", "--config", ""}, "yaml\njson\n:2\n"},
+		{[]string{"deploy", "--dry-run", ""}, ":3\n"},
+		{[]string{"db", "migrate", "up", "--steps", ""}, ":1\n"},
+		{[]string{"completion", ""}, "bash\nzsh\nfish\n:1\n"},
+	} {
+		code, out, _ := execute(&cli, append([]string{"__complete"}, c.words...)...)
+		if code != 0 || out != c.want {
+			t.Fatalf("completing %q should give %q, got %q", c.words, c.want, out)
+		}
+	}
+}
+
+func TestSetCompletionUnknownLabel(t *testing.T) {
+	h, _ := NewSubcommandHandler("x", "")
+	if err := h.SetCompletion("nope", CompleteValues("a")); err == nil {
+		t.Fatalf("setting the completion of an unknown label should fail")
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	cli := sampleCompletionCli(t)
+	for shell, want := range map[string]string{
+		"bash": "complete -F _app_completion app\n",
+		"zsh":  "compdef _app_completion app\n",
+		"fish": "complete -c app -f -a '(_app_completion)'\n",
+	} {
+		code, out, errOut := execute(&cli, "completion", shell)
+		if code != 0 {
+			t.Fatalf("completion for %s failed: %s", shell, errOut)
+		}
+		if !strings.HasSuffix(out, want) || !strings.Contains(out, "__complete") {
+			t.Fatalf("unexpected %s script:\n%s", shell, out)
+		}
+	}
+	if code, _, _ := execute(&cli, "completion", "tcsh"); code != 2 {
+		t.Fatalf("an unsupported shell should be an error")
+	}
+}
+
+func TestBashCompletionScriptRuns(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	cli := sampleCompletionCli(t)
+	var script bytes.Buffer
+	cli.writeCompletionScript(&script, "bash", "app")
+	// the program is a shell function answering the completion protocol
+	// with canned output
+	for _, c := range []struct {
+		line   string
+		answer string
+		want   string
+	}{
+		{"app d", "db\\tManage the database.\\ndeploy\\n:1", "db deploy"},
+		{"app deploy --env=p", "--env=production\\n:1", "production"},
+		{"app deploy --env ", ":3", ""},
+	} {
+		cmd := exec.Command(bash, "--norc", "-c", script.String()+
+			"\napp() { printf -- '"+c.answer+"\\n'; }\n"+
+			"COMP_LINE='"+c.line+"'; COMP_POINT=${#COMP_LINE}\n"+
+			"read -ra COMP_WORDS <<< \"${COMP_LINE//=/ = }\"; COMP_CWORD=$((${#COMP_WORDS[@]}-1))\n"+
+			"[[ \"$COMP_LINE\" == *' ' ]] && COMP_WORDS+=('') && COMP_CWORD=$((COMP_CWORD+1))\n"+
+			"_app_completion; echo \"${COMPREPLY[@]}\"")
+		cmd.Dir = t.TempDir()
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("the bash script failed: %v", err)
+		}
+		if strings.TrimSpace(string(out)) != c.want {
+			t.Fatalf("completing %q should give %q, got %q", c.line, c.want, out)
+		}
+	}
+}
+
+func TestCompletionExample(t *testing.T) {
+	cli := sampleCompletionCli(t)
+	var stdout bytes.Buffer
+	cli.Execute(context.Background(), []string{"/usr/bin/tool", "help", "completion"}, strings.NewReader(""), &stdout, &bytes.Buffer{})
+	if !strings.Contains(stdout.String(), "$ source <(tool completion bash)\n") {
+		t.Fatalf("the example should use the program name, got %q", stdout.String())
+	}
+	cli.SetName("app")
+	for name, page := range cli.ManPages() {
+		if name == "app-completion.1" && !strings.Contains(page, "source <(app completion bash)") {
+			t.Fatalf("the man page example should use the program name, got %q", page)
+		}
+	}
+	for _, subcmd := range cli.Spec().Subcommands {
+		if subcmd.Name == "completion" && subcmd.Examples[0].Command != "source <(app completion bash)" {
+			t.Fatalf("the spec example should use the program name, got %q", subcmd.Examples[0].Command)
+		}
+	}
+}
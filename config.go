@@ -0,0 +1,491 @@
+package goldcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The configuration files a CLI reads parameters from. A file that does not
+// exist is skipped. When several files set the same parameter, the System
+// file has the lowest precedence, then the User file, then the Project file,
+// then the file given with the '--config' flag. The command line and the
+// environment variables have precedence over every file.
+//
+// The format of a file is given by its name: JSON for ".json" files, dotenv
+// for ".env" files, and an INI or TOML-like format otherwise.
+type ConfigFiles struct {
+	// the path of the system-wide file e.g. "/etc/app/config.toml"
+	System string
+	// the path of the per-user file, relative to $XDG_CONFIG_HOME or, if it
+	// is not set, to ~/.config e.g. "app/config.toml"
+	User string
+	// the name of the per-project file e.g. ".app.toml", which is looked
+	// for in the working directory and then in its parents
+	Project string
+}
+
+// Read the parameters of the subcommands from configuration files.
+//
+// In a JSON file, subcommands are objects and parameters are values, by name:
+//
+//	{"divide": {"second": 2}, "db": {"migrate": {"up": {"steps": 3}}}}
+//
+// In an INI or TOML-like file, the section names the subcommand, with the
+// words of nested subcommands separated by dots, and keys name parameters:
+//
+//	[divide]
+//	second = 2
+//
+//	[db.migrate.up]
+//	steps = 3
+//
+// In a dotenv file, keys are the environment variables bound to the
+// parameters, with BindEnv or SetEnvPrefix:
+//
+//	APP_TIMEOUT=30
+//
+// Every CLI with configuration files also has a global '--config <path>'
+// flag, to read one more file with the highest precedence. Unknown keys and
+// values of the wrong type are errors, reported with the file and line.
+func (cli *Cli) SetConfigFiles(files ConfigFiles) {
+	cli.configFiles = &files
+}
+
+// A value read from a configuration file.
+type configValue struct {
+	// the file and line the value was read from
+	file string
+	line int
+	// the words of the subcommand and the label of the parameter, or the
+	// environment variable for a value from a dotenv file
+	words []string
+	label string
+	env   string
+	// the value, as it would be given on the command line
+	value string
+	// the values of a list from a JSON file, or nil if the value is not a
+	// list
+	items []string
+}
+
+// Get the key of a config value as written in a file e.g. "divide.second".
+func (v configValue) key() string {
+	if v.env != "" {
+		return v.env
+	}
+	return strings.Join(append(append([]string{}, v.words...), v.label), ".")
+}
+
+// Get the paths of the configuration files to read, lowest precedence first,
+// where `explicit` is the path given with '--config'.
+func (cli *Cli) configPaths(explicit string) []string {
+	lookup := cli.lookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	paths := make([]string, 0)
+	files := cli.configFiles
+	if files.System != "" {
+		paths = append(paths, files.System)
+	}
+	if files.User != "" {
+		if dir, ok := lookup("XDG_CONFIG_HOME"); ok && dir != "" {
+			paths = append(paths, filepath.Join(dir, files.User))
+		} else if home, ok := lookup("HOME"); ok && home != "" {
+			paths = append(paths, filepath.Join(home, ".config", files.User))
+		}
+	}
+	if files.Project != "" {
+		if dir, err := os.Getwd(); err == nil {
+			for {
+				path := filepath.Join(dir, files.Project)
+				if _, err := os.Stat(path); err == nil {
+					paths = append(paths, path)
+					break
+				}
+				parent := filepath.Dir(dir)
+				if parent == dir {
+					break
+				}
+				dir = parent
+			}
+		}
+	}
+	if explicit != "" {
+		paths = append(paths, explicit)
+	}
+	return paths
+}
+
+// Read the values of the configuration files, lowest precedence first, and
+// check them against the subcommands of the CLI. The file given with
+// '--config' must exist.
+func (cli *Cli) readConfig(explicit string) ([]configValue, error) {
+	values := make([]configValue, 0)
+	errs := make(ErrorList, 0)
+	for _, path := range cli.configPaths(explicit) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) || path == explicit {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		var read []configValue
+		switch {
+		case strings.EqualFold(filepath.Ext(path), ".json"):
+			read, err = parseJSONConfig(path, data)
+		case strings.EqualFold(filepath.Ext(path), ".env"):
+			read, err = parseDotenvConfig(path, data)
+		default:
+			read, err = parseINIConfig(path, data)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values = append(values, read...)
+	}
+	for _, v := range values {
+		if err := cli.checkConfigValue(v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return values, nil
+}
+
+// Get every subcommand of the CLI, including the hidden ones, by command
+// words joined with dots e.g. "db.migrate.up".
+func (cli *Cli) subcommandsByKey() map[string]*SubcommandHandler {
+	ret := make(map[string]*SubcommandHandler)
+	var add func(prefix string, subcmds []*SubcommandHandler)
+	add = func(prefix string, subcmds []*SubcommandHandler) {
+		for _, subcmd := range subcmds {
+			ret[prefix+subcmd.name] = subcmd
+			add(prefix+subcmd.name+".", subcmd.subcommands)
+		}
+	}
+	add("", cli.subcommands)
+	return ret
+}
+
+// Find the subcommands and parameter labels a config value applies to. A
+// value from a dotenv file applies to every parameter bound to its variable.
+func (cli *Cli) configTargets(v configValue) ([]*SubcommandHandler, []string) {
+	subcmds := make([]*SubcommandHandler, 0)
+	labels := make([]string, 0)
+	if v.env != "" {
+		cli.configureEnv()
+		byKey := cli.subcommandsByKey()
+		keys := make([]string, 0, len(byKey))
+		for key := range byKey {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			subcmd := byKey[key]
+			for _, row := range subcmd.paramparser.labelRows() {
+				if strInList(v.env, subcmd.paramparser.envNames(row[0])) {
+					subcmds = append(subcmds, subcmd)
+					labels = append(labels, row[0])
+				}
+			}
+		}
+		return subcmds, labels
+	}
+	if subcmd := cli.subcommandsByKey()[strings.Join(v.words, ".")]; subcmd != nil && subcmd.paramparser.hasAlias(v.label) {
+		subcmds = append(subcmds, subcmd)
+		labels = append(labels, v.label)
+	}
+	return subcmds, labels
+}
+
+// Check that a config value sets a parameter, with a value of its type.
+func (cli *Cli) checkConfigValue(v configValue) error {
+	where := fmt.Sprintf("%s:%d", v.file, v.line)
+	subcmds, labels := cli.configTargets(v)
+	if len(subcmds) == 0 {
+		if v.env != "" {
+			return fmt.Errorf("%s: unknown key %q, no parameter is bound to it", where, v.env)
+		}
+		subcmd := cli.subcommandsByKey()[strings.Join(v.words, ".")]
+		if subcmd == nil {
+			return fmt.Errorf("%s: unknown key %q, there is no subcommand %q", where, v.key(), strings.Join(v.words, " "))
+		}
+		if subcmd.argparser.hasAlias(v.label) {
+			return fmt.Errorf("%s: unknown key %q, %s is a required argument and must be given on the command line", where, v.key(), subcmd.argparser.flagName(v.label))
+		}
+		msg := fmt.Sprintf("%s: unknown key %q", where, v.key())
+		if s := suggest(v.label, subcmd.paramparser.allLabels); s != "" {
+			msg = msg + fmt.Sprintf(", did you mean %q?", strings.Join(append(append([]string{}, v.words...), s), "."))
+		}
+		return errors.New(msg)
+	}
+	for k, subcmd := range subcmds {
+		cp := subcmd.paramparser
+		l := cp.labelOf(labels[k])
+		_, isList := l.value.(listValue)
+		items := []string{v.value}
+		if isList && v.items != nil {
+			items = v.items
+		} else if isList {
+			items = cp.splitValue(labels[k], v.value)
+		} else if v.items != nil {
+			return fmt.Errorf("%s: invalid value for %s, expected %s, got a list", where, v.key(), l.value.Type())
+		}
+		for _, item := range items {
+			if err := l.check(item); err != nil {
+				return fmt.Errorf("%s: invalid value %q for %s, expected %s: %s", where, item, v.key(), l.value.Type(), unwrapNumError(err))
+			}
+		}
+	}
+	return nil
+}
+
+// Check that a value can be used for a label, without changing the value of
+// the label.
+func (l *label) check(s string) error {
+	s, err := explicitBool(l.value, s)
+	if err != nil {
+		return err
+	}
+	err = l.value.Set(s)
+	l.reset()
+	return err
+}
+
+// Give the config values that apply to a subcommand to it, so they are used
+// when it parses its command line.
+func (cli *Cli) applyConfig(values []configValue) {
+	for _, subcmd := range cli.subcommandsByKey() {
+		subcmd.config = nil
+	}
+	for _, v := range values {
+		subcmds, labels := cli.configTargets(v)
+		for k, subcmd := range subcmds {
+			v.label = labels[k]
+			subcmd.config = append(subcmd.config, v)
+		}
+	}
+}
+
+// Set the parameters from the config values given to the subcommand, in
+// order, so later values win.
+func (h *SubcommandHandler) parseConfig() {
+	for _, v := range h.config {
+		use := h.paramparser.useExplicit
+		if v.items != nil {
+			use = func(alias string, _ string) error {
+				return h.paramparser.useItems(alias, v.items)
+			}
+		}
+		if use(v.label, v.value) == nil {
+			h.paramparser.sources[h.paramparser.aliasesOf(v.label)[0]] = Source{Kind: SourceConfig, File: v.file, Line: v.line}
+		}
+	}
+}
+
+// Print the errors in the configuration files to `w`, and return the exit
+// status for them.
+func printConfigErrors(w io.Writer, err error) int {
+	if list, ok := err.(ErrorList); ok {
+		for _, e := range list {
+			fmt.Fprintf(w, "error: %s\n", e)
+		}
+	} else {
+		fmt.Fprintf(w, "error: %s\n", err)
+	}
+	return 2
+}
+
+// Get the line number of a byte offset in a file.
+func lineOf(data []byte, offset int64) int {
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// Parse a JSON configuration file, where objects are subcommands and other
+// values set parameters.
+func parseJSONConfig(file string, data []byte) ([]configValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	values := make([]configValue, 0)
+	fail := func(err error) ([]configValue, error) {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			return nil, fmt.Errorf("%s:%d: %s", file, lineOf(data, syntaxErr.Offset), err)
+		}
+		return nil, fmt.Errorf("%s:%d: %s", file, lineOf(data, dec.InputOffset()), err)
+	}
+	// an error that already has the file and line
+	var done error
+	// read the values of a list whose opening bracket has been read, for
+	// the config value `v`
+	readList := func(v configValue) ([]string, error) {
+		items := make([]string, 0)
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch item := token.(type) {
+			case json.Number:
+				items = append(items, item.String())
+			case bool:
+				items = append(items, strconv.FormatBool(item))
+			case string:
+				items = append(items, item)
+			default:
+				done = fmt.Errorf("%s:%d: expected a list of values for %q", file, lineOf(data, dec.InputOffset()), v.key())
+				return nil, done
+			}
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+	// read an object whose opening brace has been read, where `words` are
+	// the keys leading to it
+	var readObject func(words []string) error
+	readObject = func(words []string) error {
+		for dec.More() {
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			offset := dec.InputOffset()
+			token, err = dec.Token()
+			if err != nil {
+				return err
+			}
+			// the offset is just after the key, so it is on the line of
+			// the key unless the value starts on a later line
+			line := lineOf(data, offset)
+			v := configValue{file: file, line: line, words: words, label: key}
+			switch value := token.(type) {
+			case json.Delim:
+				if value == '[' {
+					if v.items, err = readList(v); err != nil {
+						return err
+					}
+					break
+				}
+				if err := readObject(append(append([]string{}, words...), key)); err != nil {
+					return err
+				}
+				continue
+			case json.Number:
+				v.value = value.String()
+			case bool:
+				v.value = strconv.FormatBool(value)
+			case string:
+				v.value = value
+			case nil:
+				continue
+			}
+			values = append(values, v)
+		}
+		_, err := dec.Token()
+		return err
+	}
+	token, err := dec.Token()
+	if err != nil {
+		return fail(err)
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("%s:1: expected an object", file)
+	}
+	if err := readObject(nil); err != nil && err == done {
+		return nil, err
+	} else if err != nil {
+		return fail(err)
+	}
+	return values, nil
+}
+
+// Remove the quotes around a value from an INI or dotenv file, or the
+// comment after an unquoted value.
+func unquoteConfigValue(value string) (string, error) {
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if s, ok := unquoteYAML(value); ok {
+			return s, nil
+		}
+		return "", fmt.Errorf("invalid quoted string %s", value)
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// Parse an INI or TOML-like configuration file, where sections are
+// subcommands e.g. "[db.migrate]" and keys are parameters.
+func parseINIConfig(file string, data []byte) ([]configValue, error) {
+	values := make([]configValue, 0)
+	section := []string(nil)
+	for k, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated section name", file, k+1)
+			}
+			if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+				return nil, fmt.Errorf("%s:%d: unexpected text %q after the section name, a key and a value go on their own line", file, k+1, rest)
+			}
+			section = strings.FieldsFunc(line[1:end], func(r rune) bool { return r == '.' || r == ' ' })
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("%s:%d: expected a key and a value e.g. \"key = value\"", file, k+1)
+		}
+		key := strings.TrimSpace(line[:eq])
+		value, err := unquoteConfigValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", file, k+1, err)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("%s:%d: missing key", file, k+1)
+		}
+		// dotted keys e.g. "divide.second = 2" name a subcommand too
+		parts := strings.Split(key, ".")
+		words := append(append([]string{}, section...), parts[:len(parts)-1]...)
+		values = append(values, configValue{file: file, line: k + 1, words: words, label: parts[len(parts)-1], value: value})
+	}
+	return values, nil
+}
+
+// Parse a dotenv configuration file, where keys are environment variables.
+func parseDotenvConfig(file string, data []byte) ([]configValue, error) {
+	values := make([]configValue, 0)
+	for k, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected a variable and a value e.g. \"KEY=value\"", file, k+1)
+		}
+		value, err := unquoteConfigValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", file, k+1, err)
+		}
+		values = append(values, configValue{file: file, line: k + 1, env: strings.TrimSpace(line[:eq]), value: value})
+	}
+	return values, nil
+}
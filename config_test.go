@@ -0,0 +1,156 @@
+package goldcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sampleConfigCli(t *testing.T, env map[string]string) Cli {
+	cli := NewCli("1.0", "A CLI reading configuration files.")
+	divide, _ := NewSubcommandHandler("divide", "Divide two numbers.")
+	divide.AddFloatArg([]string{"first"}, "the dividend")
+	divide.AddFloatParamWithDefault([]string{"second", "s"}, "the divisor", 1)
+	divide.AddIntParamWithDefault([]string{"precision"}, "the number of decimals", 2)
+	divide.BindEnv("precision", "PRECISION")
+	divide.Handle(func(h *SubcommandHandler) {
+		first, _ := h.GetFloat("first")
+		second, _ := h.GetFloat("second")
+		precision, _ := h.GetInt("precision")
+		fmt.Fprintf(h.Stdout(), "%.*f", precision, first/second)
+	})
+	cli.HandleSubcommand(divide)
+	cli.SetLookupEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	return cli
+}
+
+func writeConfig(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("could not create directory: %s", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("could not write file: %s", err)
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg")}
+	cli := sampleConfigCli(t, env)
+	cli.SetConfigFiles(ConfigFiles{
+		System:  filepath.Join(dir, "etc", "calc.toml"),
+		User:    "calc/config.json",
+		Project: ".calc.env",
+	})
+	writeConfig(t, filepath.Join(dir, "etc", "calc.toml"), "# system\n[divide]\nsecond = 4\nprecision = 0\n")
+	if _, out, errOut := execute(&cli, "divide", "--first", "10"); out != "2" {
+		t.Fatalf("the system file should be read, got %q %q", out, errOut)
+	}
+	writeConfig(t, filepath.Join(dir, "xdg", "calc", "config.json"), `{"divide": {"precision": 1}}`)
+	if _, out, _ := execute(&cli, "divide", "--first", "10"); out != "2.5" {
+		t.Fatalf("the user file should override the system file, got %q", out)
+	}
+	project := filepath.Join(dir, "project", "sub")
+	writeConfig(t, filepath.Join(dir, "project", ".calc.env"), "PRECISION=3\n")
+	os.MkdirAll(project, 0755)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(project)
+	if _, out, _ := execute(&cli, "divide", "--first", "10"); out != "2.500" {
+		t.Fatalf("the project file should be found in a parent directory, got %q", out)
+	}
+	explicit := filepath.Join(dir, "explicit.ini")
+	writeConfig(t, explicit, "divide.second = 5 # dotted key\n")
+	if _, out, _ := execute(&cli, "--config", explicit, "divide", "--first", "10"); out != "2.000" {
+		t.Fatalf("the explicit file should override the others, got %q", out)
+	}
+	env["PRECISION"] = "0"
+	if _, out, _ := execute(&cli, "divide", "--first", "10", "--config="+explicit); out != "2" {
+		t.Fatalf("the environment should override the files, got %q", out)
+	}
+	if _, out, _ := execute(&cli, "divide", "--first", "10", "-s", "2", "--config", explicit); out != "5" {
+		t.Fatalf("the command line should override the files, got %q", out)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	cli := sampleConfigCli(t, nil)
+	cli.SetConfigFiles(ConfigFiles{})
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"a.toml", "[divide]\n\nsecnd = 2\n", `a.toml:3: unknown key "divide.secnd", did you mean "divide.second"?`},
+		{"b.toml", "[divide]\nsecond = two\n", `b.toml:2: invalid value "two" for divide.second, expected float: invalid syntax`},
+		{"c.toml", "[multiply]\nsecond = 2\n", `c.toml:2: unknown key "multiply.second", there is no subcommand "multiply"`},
+		{"d.toml", "[divide]\nfirst = 2\n", `d.toml:2: unknown key "divide.first", --first is a required argument and must be given on the command line`},
+		{"e.json", "{\n  \"divide\": {\n    \"precision\": 1.5\n  }\n}", `e.json:3: invalid value "1.5" for divide.precision, expected int: invalid syntax`},
+		{"f.json", "{\n  \"divide\": {\n    \"precision\": 1,\n  }\n}", `f.json:3: invalid character ',' looking for beginning of value`},
+		{"g.env", "TIMEOUT=3\n", `g.env:1: unknown key "TIMEOUT", no parameter is bound to it`},
+		{"h.toml", "\n[divide] second = 2\n", `h.toml:2: unexpected text "second = 2" after the section name, a key and a value go on their own line`},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		writeConfig(t, path, test.content)
+		code, _, errOut := execute(&cli, "divide", "--first", "1", "--config", path)
+		if code != 2 || !strings.Contains(errOut, "error: "+filepath.Join(dir, test.err)+"\n") {
+			t.Fatalf("expected error %q, got %q", test.err, errOut)
+		}
+	}
+	path := filepath.Join(dir, "comment.toml")
+	writeConfig(t, path, "[divide] # the divisor\nsecond = 2\n")
+	if code, out, errOut := execute(&cli, "divide", "--first", "1", "--config", path); code != 0 || out != "0.50" {
+		t.Fatalf("a comment after a section name should be allowed, got %d %q %q", code, out, errOut)
+	}
+	code, _, errOut := execute(&cli, "divide", "--first", "1", "--config", filepath.Join(dir, "missing.toml"))
+	if code != 2 || !strings.Contains(errOut, "no such file") {
+		t.Fatalf("a missing explicit file should be an error, got %q", errOut)
+	}
+}
+
+func TestConfigFlagCollision(t *testing.T) {
+	cli := sampleConfigCli(t, nil)
+	sub, _ := NewSubcommandHandler("load", "Load something.")
+	sub.AddStrParamWithDefault([]string{"config"}, "what to load", "")
+	cli.HandleSubcommand(sub)
+	if err := cli.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cli.SetConfigFiles(ConfigFiles{})
+	if err := cli.Validate(); err == nil || !strings.Contains(err.Error(), "the alias \"config\" is used by the global --config flag") {
+		t.Fatalf("expected a collision error, got %v", err)
+	}
+}
+
+func TestConfigBool(t *testing.T) {
+	dir := t.TempDir()
+	cli := sampleConfigCli(t, nil)
+	cli.SetConfigFiles(ConfigFiles{})
+	divide := cli.subcommands[0]
+	divide.AddBoolParamWithDefault([]string{"dry"}, "only pretend", true)
+	divide.Handle(func(h *SubcommandHandler) {
+		dry, _ := h.GetBool("dry")
+		fmt.Fprintf(h.Stdout(), "%t", dry)
+	})
+	path := filepath.Join(dir, "app.toml")
+	for value, want := range map[string]string{"0": "false", "false": "false", "1": "true"} {
+		writeConfig(t, path, "[divide]\ndry = "+value+"\n")
+		if code, out, errOut := execute(&cli, "divide", "--first", "1", "--config", path); code != 0 || out != want {
+			t.Fatalf("dry = %s: expected %q, got %d %q %q", value, want, code, out, errOut)
+		}
+	}
+	for _, value := range []string{"no", "garbage"} {
+		writeConfig(t, path, "[divide]\ndry = "+value+"\n")
+		want := fmt.Sprintf("error: %s:2: invalid value %q for divide.dry, expected bool: invalid syntax\n", path, value)
+		if code, _, errOut := execute(&cli, "divide", "--first", "1", "--config", path); code != 2 || !strings.Contains(errOut, want) {
+			t.Fatalf("expected %q, got %d %q", want, code, errOut)
+		}
+	}
+}
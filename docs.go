@@ -0,0 +1,327 @@
+package goldcmd
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// The documentation of one argument or option of a subcommand, as rendered in
+// the reference documentation.
+type docEntry struct {
+	// the anchor of the entry within the page of its subcommand e.g.
+	// "flag-steps" or "arg-src"
+	anchor string
+	// the ways of writing the entry on the command line e.g. "--steps", or
+	// "<src>" for a positional argument
+	names []string
+	// the type name of the value e.g. "int", or an empty string for a flag
+	// that takes no value
+	typeName string
+	// the default value, if there is one
+	deflt      string
+	hasDefault bool
+	// the environment variables that set the value
+	env []string
+	// documentation for the entry
+	documentation string
+}
+
+// Get the documentation entries of the labels of a parser, in the order they
+// were added.
+func flagEntries(cp *commandParser) []docEntry {
+	entries := make([]docEntry, 0)
+	for _, row := range cp.labelRows() {
+		e := docEntry{anchor: "flag-" + row[0], documentation: cp.docOf(row[0])}
+		for _, alias := range row {
+			e.names = append(e.names, cp.flagName(alias))
+		}
+		if !cp.isBool(row[0]) {
+			e.typeName = cp.labelType(row[0])
+		}
+		e.deflt, e.hasDefault = cp.defaultOf(row[0])
+		e.env = cp.envNames(row[0])
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Get the documentation entries of the positional arguments and required
+// flags of a subcommand.
+func (h *SubcommandHandler) argumentEntries() []docEntry {
+	entries := make([]docEntry, 0)
+	for _, p := range h.positionals {
+		e := docEntry{anchor: "arg-" + p.name, names: []string{p.usageToken()}, typeName: p.typeName, documentation: p.documentation}
+		if p.optional {
+			e.deflt, e.hasDefault = h.posparser.defaultOf(p.name)
+		}
+		entries = append(entries, e)
+	}
+	return append(entries, flagEntries(h.argparser)...)
+}
+
+// Get the documentation entries of the options of a subcommand, including
+// the built-in help flag.
+func (h *SubcommandHandler) optionEntries() []docEntry {
+	entries := flagEntries(h.paramparser)
+	return append(entries, docEntry{anchor: "flag-help", names: []string{"--help", "-h"}, documentation: "print help"})
+}
+
+// Get the notes about the value of an entry e.g. "int, default 1, env
+// APP_STEPS".
+func (e docEntry) notes() string {
+	notes := make([]string, 0)
+	if e.typeName != "" {
+		notes = append(notes, e.typeName)
+	}
+	if e.hasDefault && e.typeName == "str" {
+		notes = append(notes, fmt.Sprintf("default %q", e.deflt))
+	} else if e.hasDefault {
+		notes = append(notes, "default "+e.deflt)
+	}
+	if len(e.env) > 0 {
+		notes = append(notes, "env "+strings.Join(e.env, ", "))
+	}
+	return strings.Join(notes, ", ")
+}
+
+// Get the name of the Markdown file for a command path e.g. "app-db-migrate.md".
+func markdownFileName(path []string) string {
+	return strings.Join(path, "-") + ".md"
+}
+
+// Get a Markdown list of documentation entries.
+func markdownEntries(entries []docEntry) string {
+	s := ""
+	for _, e := range entries {
+		names := make([]string, 0, len(e.names))
+		for _, name := range e.names {
+			names = append(names, "`"+name+"`")
+		}
+		s = s + fmt.Sprintf("- <a id=\"%s\"></a>%s", e.anchor, strings.Join(names, ", "))
+		if notes := e.notes(); notes != "" {
+			s = s + " (" + notes + ")"
+		}
+		s = s + ": " + e.documentation + "\n"
+	}
+	return s
+}
+
+// Get the Markdown page for the subcommand at `path`, which holds the program
+// name and the command words leading to the subcommand.
+func (h *SubcommandHandler) markdownPage(path []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", strings.Join(path, " "), h.documentation)
+	fmt.Fprintf(&b, "```\n%s\n```\n\n", h.usage(path))
+	if children := visible(h.subcommands); len(children) > 0 {
+		fmt.Fprintf(&b, "## Subcommands\n\n")
+		for _, child := range children {
+			childPath := append(append([]string{}, path...), child.name)
+			fmt.Fprintf(&b, "- [`%s`](%s): %s\n", child.name, markdownFileName(childPath), child.documentation)
+		}
+		fmt.Fprintf(&b, "\n")
+	}
+	if args := h.argumentEntries(); len(args) > 0 {
+		fmt.Fprintf(&b, "## Arguments\n\n%s\n", markdownEntries(args))
+	}
+	fmt.Fprintf(&b, "## Options\n\n%s\n", markdownEntries(h.optionEntries()))
+	if len(h.examples) > 0 {
+		fmt.Fprintf(&b, "## Examples\n\n")
+		for _, ex := range h.examples {
+			if ex.documentation != "" {
+				fmt.Fprintf(&b, "%s\n\n", ex.documentation)
+			}
+			fmt.Fprintf(&b, "```\n$ %s\n", ex.commandLine(path[0]))
+			if ex.output != "" {
+				fmt.Fprintf(&b, "%s\n", ex.output)
+			}
+			fmt.Fprintf(&b, "```\n\n")
+		}
+	}
+	parent := path[:len(path)-1]
+	fmt.Fprintf(&b, "## See also\n\n- [%s](%s)\n", strings.Join(parent, " "), markdownFileName(parent))
+	return b.String()
+}
+
+// Get the top-level Markdown page for the CLI, with a table of contents
+// linking to the page of every subcommand.
+func (cli *Cli) markdownIndex(prog string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", prog, cli.documentation)
+	fmt.Fprintf(&b, "```\nusage: %s <subcommand> [arguments]\n```\n\n", prog)
+	fmt.Fprintf(&b, "## Contents\n\n")
+	cli.walk(prog, func(path []string, h *SubcommandHandler) {
+		indent := strings.Repeat("  ", len(path)-2)
+		fmt.Fprintf(&b, "%s- [%s](%s): %s\n", indent, strings.Join(path, " "), markdownFileName(path), firstLine(h.documentation))
+	})
+	return b.String()
+}
+
+// Get the reference documentation for the CLI as linked Markdown files, by
+// file name: one index named after the program e.g. "app.md", with a table of
+// contents, and one page per subcommand e.g. "app-db-migrate.md". Every
+// argument and option has an anchor e.g. "app-db-migrate-up.md#flag-steps".
+// Hidden subcommands are left out.
+func (cli *Cli) MarkdownDocs() map[string]string {
+	return cli.markdownDocs(cli.progName(nil))
+}
+
+// Get the Markdown documentation for the CLI, where `prog` is the program
+// name.
+func (cli *Cli) markdownDocs(prog string) map[string]string {
+	cli.configureEnv()
+	files := map[string]string{prog + ".md": cli.markdownIndex(prog)}
+	cli.walk(prog, func(path []string, h *SubcommandHandler) {
+		files[markdownFileName(path)] = h.markdownPage(path)
+	})
+	return files
+}
+
+// The style sheet of the HTML documentation.
+const htmlStyle = `body { font-family: sans-serif; margin: 0; display: flex; }
+nav { width: 16em; padding: 1em; border-right: 1px solid #ddd; height: 100vh; overflow: auto; position: sticky; top: 0; box-sizing: border-box; }
+nav ul { list-style: none; padding-left: 1em; margin: 0; }
+main { flex: 1; padding: 1em 2em; max-width: 50em; }
+section { border-bottom: 1px solid #eee; padding-bottom: 1em; }
+pre { background: #f6f8fa; padding: 0.5em; overflow: auto; }
+dt { font-family: monospace; margin-top: 0.5em; }
+dt a { color: inherit; text-decoration: none; }
+dd { margin-left: 2em; }
+.notes { color: #666; font-family: sans-serif; }
+`
+
+// Get an HTML definition list of documentation entries, where `id` is the
+// anchor of the subcommand they belong to.
+func htmlEntries(id string, entries []docEntry) string {
+	s := "<dl>\n"
+	for _, e := range entries {
+		anchor := id + "--" + e.anchor
+		names := make([]string, 0, len(e.names))
+		for _, name := range e.names {
+			names = append(names, "<code>"+html.EscapeString(name)+"</code>")
+		}
+		s = s + fmt.Sprintf("<dt id=\"%s\"><a href=\"#%s\">%s</a>", html.EscapeString(anchor), html.EscapeString(anchor), strings.Join(names, ", "))
+		if notes := e.notes(); notes != "" {
+			s = s + " <span class=\"notes\">(" + html.EscapeString(notes) + ")</span>"
+		}
+		s = s + "</dt>\n<dd>" + html.EscapeString(e.documentation) + "</dd>\n"
+	}
+	return s + "</dl>\n"
+}
+
+// Get the HTML section for the subcommand at `path`, which holds the program
+// name and the command words leading to the subcommand.
+func (h *SubcommandHandler) htmlSection(path []string) string {
+	id := html.EscapeString(strings.Join(path, "-"))
+	var b strings.Builder
+	fmt.Fprintf(&b, "<section id=\"%s\">\n<h2><a href=\"#%s\">%s</a></h2>\n", id, id, html.EscapeString(strings.Join(path, " ")))
+	fmt.Fprintf(&b, "<p>%s</p>\n<pre>%s</pre>\n", html.EscapeString(h.documentation), html.EscapeString(h.usage(path)))
+	if children := visible(h.subcommands); len(children) > 0 {
+		fmt.Fprintf(&b, "<h3>Subcommands</h3>\n<dl>\n")
+		for _, child := range children {
+			childID := html.EscapeString(strings.Join(append(append([]string{}, path...), child.name), "-"))
+			fmt.Fprintf(&b, "<dt><a href=\"#%s\">%s</a></dt>\n<dd>%s</dd>\n", childID, html.EscapeString(child.name), html.EscapeString(child.documentation))
+		}
+		fmt.Fprintf(&b, "</dl>\n")
+	}
+	if args := h.argumentEntries(); len(args) > 0 {
+		fmt.Fprintf(&b, "<h3>Arguments</h3>\n%s", htmlEntries(id, args))
+	}
+	fmt.Fprintf(&b, "<h3>Options</h3>\n%s", htmlEntries(id, h.optionEntries()))
+	if len(h.examples) > 0 {
+		fmt.Fprintf(&b, "<h3>Examples</h3>\n")
+		for _, ex := range h.examples {
+			if ex.documentation != "" {
+				fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(ex.documentation))
+			}
+			fmt.Fprintf(&b, "<pre>$ %s", html.EscapeString(ex.commandLine(path[0])))
+			if ex.output != "" {
+				fmt.Fprintf(&b, "\n%s", html.EscapeString(ex.output))
+			}
+			fmt.Fprintf(&b, "</pre>\n")
+		}
+	}
+	fmt.Fprintf(&b, "</section>\n")
+	return b.String()
+}
+
+// Get the reference documentation for the CLI as a single, self-contained
+// HTML page, with a table of contents, a section per subcommand, and an
+// anchor per argument and option e.g. "#app-db-migrate-up--flag-steps".
+// Hidden subcommands are left out.
+func (cli *Cli) HTMLDocs() string {
+	return cli.htmlDocs(cli.progName(nil))
+}
+
+// Get the HTML documentation for the CLI, where `prog` is the program name.
+func (cli *Cli) htmlDocs(prog string) string {
+	cli.configureEnv()
+	title := html.EscapeString(prog)
+	var toc, sections strings.Builder
+	// the depth of the previous item, whose list item is left open so that
+	// the list of its children can be nested in it
+	depth := 0
+	cli.walk(prog, func(path []string, h *SubcommandHandler) {
+		if len(path)-1 > depth {
+			toc.WriteString("\n<ul>\n")
+		} else {
+			toc.WriteString("</li>\n")
+		}
+		for ; depth > len(path)-1; depth-- {
+			toc.WriteString("</ul>\n</li>\n")
+		}
+		depth = len(path) - 1
+		id := html.EscapeString(strings.Join(path, "-"))
+		fmt.Fprintf(&toc, "<li><a href=\"#%s\">%s</a>", id, html.EscapeString(strings.Join(path[1:], " ")))
+		sections.WriteString(h.htmlSection(path))
+	})
+	for ; depth > 0; depth-- {
+		toc.WriteString("</li>\n</ul>\n")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s %s</title>\n<style>\n%s</style>\n</head>\n<body>\n", title, html.EscapeString(cli.version), htmlStyle)
+	fmt.Fprintf(&b, "<nav>\n<h2><a href=\"#%s\">%s</a></h2>%s</nav>\n", title, title, toc.String())
+	fmt.Fprintf(&b, "<main>\n<section id=\"%s\">\n<h1>%s</h1>\n<p>%s</p>\n", title, title, html.EscapeString(cli.documentation))
+	fmt.Fprintf(&b, "<pre>usage: %s &lt;subcommand&gt; [arguments]</pre>\n</section>\n", title)
+	b.WriteString(sections.String())
+	fmt.Fprintf(&b, "</main>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// Write the reference documentation for the CLI to a directory, which is
+// created if it does not exist: the Markdown files of MarkdownDocs and the
+// HTML page of HTMLDocs as "index.html". The paths of the written files are
+// returned in order.
+func (cli *Cli) WriteDocs(dir string) ([]string, error) {
+	return cli.writeDocs(dir, cli.progName(nil))
+}
+
+// Write the reference documentation for the CLI to a directory, where `prog`
+// is the program name.
+func (cli *Cli) writeDocs(dir string, prog string) ([]string, error) {
+	files := cli.markdownDocs(prog)
+	files["index.html"] = cli.htmlDocs(prog)
+	return writeFiles(dir, files)
+}
+
+// Get the built-in, hidden 'gen-docs' subcommand, which writes the reference
+// documentation to a directory.
+func (cli *Cli) genDocsSubcommand() *SubcommandHandler {
+	h, _ := NewSubcommandHandler("gen-docs", "write the Markdown and HTML reference documentation of this app to a directory")
+	h.AddStrPositional("dir", "the directory to write the documentation to")
+	h.SetCompletion("dir", CompleteDirectoryNames())
+	h.SetHidden(true)
+	h.Handle(func(h *SubcommandHandler) {
+		dir, _ := h.GetStr("dir")
+		written, err := cli.writeDocs(dir, h.path[0])
+		for _, path := range written {
+			fmt.Fprintf(h.Stdout(), "%s\n", path)
+		}
+		if err != nil {
+			fmt.Fprintf(h.Stderr(), "error: %s\n", err)
+			h.SetExitCode(1)
+		}
+	})
+	return h
+}
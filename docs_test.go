@@ -0,0 +1,79 @@
+package goldcmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownDocs(t *testing.T) {
+	cli, _ := sampleTreeCli(t)
+	cli.SetName("app")
+	files := cli.MarkdownDocs()
+	if _, ok := files["app-gen-docs.md"]; ok {
+		t.Fatalf("hidden subcommands should not be documented")
+	}
+	index := files["app.md"]
+	if !strings.Contains(index, "- [app db](app-db.md): Manage the database.\n  - [app db migrate](app-db-migrate.md): Run migrations.\n") {
+		t.Fatalf("the index should hold a nested table of contents:\n%s", index)
+	}
+	page, ok := files["app-db-migrate-up.md"]
+	if !ok {
+		t.Fatalf("missing page for 'app db migrate up'")
+	}
+	for _, want := range []string{
+		"# app db migrate up\n\nApply migrations.\n",
+		"usage: app db migrate up [OPTIONS]",
+		"- <a id=\"flag-steps\"></a>`--steps` (int, default 1): how many migrations to apply\n",
+		"- [app db migrate](app-db-migrate.md)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("expected %q in page:\n%s", want, page)
+		}
+	}
+	if !strings.Contains(files["app-db.md"], "- [`migrate`](app-db-migrate.md): Run migrations.") {
+		t.Fatalf("a group page should link to its children:\n%s", files["app-db.md"])
+	}
+}
+
+func TestHTMLDocs(t *testing.T) {
+	cli := NewCli("1.0", "An <app> & more.")
+	cli.SetName("app")
+	cp, _ := NewSubcommandHandler("cp", "Copy files.")
+	cp.AddStrPositional("src", "the file to copy")
+	cp.AddStrParamWithDefault([]string{"mode", "m"}, "the file mode", "0644")
+	cp.Example("copy a file", "app cp a.txt", "")
+	cp.Handle(func(h *SubcommandHandler) {})
+	cli.HandleSubcommand(cp)
+	page := cli.HTMLDocs()
+	for _, want := range []string{
+		"<p>An &lt;app&gt; &amp; more.</p>",
+		"<li><a href=\"#app-cp\">cp</a></li>",
+		"<section id=\"app-cp\">",
+		"<dt id=\"app-cp--arg-src\"><a href=\"#app-cp--arg-src\"><code>&lt;src&gt;</code></a> <span class=\"notes\">(str)</span></dt>",
+		"<dt id=\"app-cp--flag-mode\"><a href=\"#app-cp--flag-mode\"><code>--mode</code>, <code>--m</code></a> <span class=\"notes\">(str, default &#34;0644&#34;)</span></dt>",
+		"<pre>$ app cp a.txt</pre>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("expected %q in page:\n%s", want, page)
+		}
+	}
+	if !strings.Contains(page, "<style>") || strings.Contains(page, "<link") {
+		t.Fatalf("the page should be self-contained")
+	}
+}
+
+func TestGenDocs(t *testing.T) {
+	cli, _ := sampleTreeCli(t)
+	dir := t.TempDir()
+	code, _, errOut := execute(&cli, "gen-docs", dir)
+	if code != 0 {
+		t.Fatalf("unexpected failure %q", errOut)
+	}
+	for _, name := range []string{"index.html", "app.md", "app-db-migrate-up.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected %s to be written: %s", name, err)
+		}
+	}
+}
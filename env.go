@@ -0,0 +1,62 @@
+package goldcmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// Bind a label to one or more environment variables, which set its value
+// when it is not given on the command line. The label can be an argument or
+// a parameter. The variables are looked up in order and the first one that is
+// set and not empty is used, with the same conversion as a command line
+// value. The variables are listed in the help message.
+func (h *SubcommandHandler) BindEnv(label string, names ...string) error {
+	cp := parserForAlias(label, []*commandParser{h.argparser, h.paramparser})
+	if cp == nil {
+		return fmt.Errorf("unknown label \"%s\"", label)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no environment variable to bind %s to", cp.flagName(label))
+	}
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("empty environment variable name for %s", cp.flagName(label))
+		}
+	}
+	row := cp.aliasesOf(label)
+	cp.env[row[0]] = append(cp.env[row[0]], names...)
+	return nil
+}
+
+// Bind every parameter of every subcommand to the environment variable named
+// after `prefix` and the first alias of the parameter, in upper case with
+// hyphens replaced by underscores, e.g. "APP_DRY_RUN" for "--dry-run" with
+// the prefix "APP". Variables bound with BindEnv are looked up first. The
+// built-in subcommands are not bound.
+func (cli *Cli) SetEnvPrefix(prefix string) {
+	cli.envPrefix = prefix
+}
+
+// Set the function used to look up environment variables, which is
+// os.LookupEnv by default. This is useful to test a CLI without changing the
+// environment of the process.
+func (cli *Cli) SetLookupEnv(lookup func(key string) (string, bool)) {
+	cli.lookupEnv = lookup
+}
+
+// Pass the environment settings of the CLI to every subcommand.
+func (cli *Cli) configureEnv() {
+	lookup := cli.lookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var configure func(subcmds []*SubcommandHandler)
+	configure = func(subcmds []*SubcommandHandler) {
+		for _, subcmd := range subcmds {
+			subcmd.paramparser.envPrefix = cli.envPrefix
+			subcmd.lookupEnv = lookup
+			configure(subcmd.subcommands)
+		}
+	}
+	configure(cli.subcommands)
+}
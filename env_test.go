@@ -0,0 +1,117 @@
+package goldcmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func sampleEnvCli(t *testing.T, env map[string]string) Cli {
+	cli := NewCli("1.0", "A CLI reading the environment.")
+	sub, _ := NewSubcommandHandler("wait", "Wait for a while.")
+	sub.AddIntParamWithDefault([]string{"timeout", "t"}, "how long to wait", 10)
+	sub.AddStrParamWithDefault([]string{"dry-run"}, "what to pretend", "nothing")
+	sub.AddStrArg([]string{"target"}, "what to wait for")
+	if err := sub.BindEnv("target", "WAIT_TARGET", "TARGET"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sub.Handle(func(h *SubcommandHandler) {
+		timeout, _ := h.GetInt("timeout")
+		target, _ := h.GetStr("target")
+		dryRun, _ := h.GetStr("dry-run")
+		fmt.Fprintf(h.Stdout(), "%s %d %s", target, timeout, dryRun)
+	})
+	cli.HandleSubcommand(sub)
+	cli.SetEnvPrefix("APP")
+	cli.SetLookupEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	return cli
+}
+
+func TestEnvPrecedence(t *testing.T) {
+	env := map[string]string{"APP_TIMEOUT": "30", "APP_DRY_RUN": "all", "TARGET": "db"}
+	cli := sampleEnvCli(t, env)
+	if code, out, errOut := execute(&cli, "wait"); code != 0 || out != "db 30 all" {
+		t.Fatalf("the environment should override the defaults, got %d %q %q", code, out, errOut)
+	}
+	if _, out, _ := execute(&cli, "wait", "-t", "5", "--target", "cache"); out != "cache 5 all" {
+		t.Fatalf("the command line should override the environment, got %q", out)
+	}
+	env["WAIT_TARGET"] = "queue"
+	if _, out, _ := execute(&cli, "wait"); out != "queue 30 all" {
+		t.Fatalf("the first variable bound should win, got %q", out)
+	}
+	delete(env, "APP_TIMEOUT")
+	if _, out, _ := execute(&cli, "wait"); out != "queue 10 all" {
+		t.Fatalf("the default should be used without a variable, got %q", out)
+	}
+}
+
+func TestEnvErrors(t *testing.T) {
+	cli := sampleEnvCli(t, map[string]string{"APP_TIMEOUT": "soon"})
+	code, _, errOut := execute(&cli, "wait")
+	if code != 2 || !strings.Contains(errOut, "error: invalid value \"soon\" for --timeout from $APP_TIMEOUT, expected int: invalid syntax") {
+		t.Fatalf("unexpected error output %q", errOut)
+	}
+	if !strings.Contains(errOut, "missing required argument --target") {
+		t.Fatalf("a required argument without a variable should be missing, got %q", errOut)
+	}
+	if code, _, _ := execute(&cli, "wait", "--timeout", "1", "--target", "x"); code != 0 {
+		t.Fatalf("an invalid variable should be ignored when the flag is given")
+	}
+	sub, _ := NewSubcommandHandler("sub", "A subcommand.")
+	if err := sub.BindEnv("nope", "NOPE"); err == nil {
+		t.Fatalf("expected an error for an unknown label")
+	}
+}
+
+func TestEnvHelp(t *testing.T) {
+	cli := sampleEnvCli(t, nil)
+	_, out, _ := execute(&cli, "help", "wait")
+	if !strings.Contains(out, " --timeout, --t <int>\thow long to wait [env: APP_TIMEOUT]\n") {
+		t.Fatalf("the help should list the variables, got %q", out)
+	}
+	if !strings.Contains(out, " --target <str>\twhat to wait for [env: WAIT_TARGET, TARGET]\n") {
+		t.Fatalf("the help should list the variables of arguments, got %q", out)
+	}
+	if labels := cli.Spec().Subcommands[0].Labels; strings.Join(labels[1].Env, ",") != "APP_TIMEOUT" {
+		t.Fatalf("the spec should list the variables, got %+v", labels)
+	}
+}
+
+func TestEnvParse(t *testing.T) {
+	cli := sampleEnvCli(t, map[string]string{"APP_TIMEOUT": "7", "TARGET": "db"})
+	subcmd, err := cli.Parse([]string{"app", "wait"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if timeout, _ := subcmd.GetInt("timeout"); timeout != 7 {
+		t.Fatalf("Parse should read the environment, got %d", timeout)
+	}
+}
+
+func TestEnvBool(t *testing.T) {
+	env := map[string]string{"TARGET": "db"}
+	cli := sampleEnvCli(t, env)
+	sub := cli.subcommands[0]
+	sub.AddBoolParamWithDefault([]string{"dry"}, "only pretend", true)
+	sub.Handle(func(h *SubcommandHandler) {
+		dry, _ := h.GetBool("dry")
+		fmt.Fprintf(h.Stdout(), "%t", dry)
+	})
+	for value, want := range map[string]string{"0": "false", "false": "false", "F": "false", "1": "true", "true": "true"} {
+		env["APP_DRY"] = value
+		if code, out, errOut := execute(&cli, "wait"); code != 0 || out != want {
+			t.Fatalf("APP_DRY=%s: expected %q, got %d %q %q", value, want, code, out, errOut)
+		}
+	}
+	for _, value := range []string{"no", "garbage"} {
+		env["APP_DRY"] = value
+		want := fmt.Sprintf("error: invalid value %q for --dry from $APP_DRY, expected bool: invalid syntax", value)
+		if code, _, errOut := execute(&cli, "wait"); code != 2 || !strings.Contains(errOut, want) {
+			t.Fatalf("expected %q, got %d %q", want, code, errOut)
+		}
+	}
+}
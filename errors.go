@@ -0,0 +1,152 @@
+package goldcmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A list of errors reported together, e.g. every problem found with a
+// command line. Each error is rendered on its own line.
+type ErrorList []error
+
+// Get the error message for the whole list.
+func (l ErrorList) Error() string {
+	msgs := make([]string, 0, len(l))
+	for _, err := range l {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// A ParseError describes a command line token that could not be used.
+type ParseError struct {
+	// the full command line the token was taken from
+	Args []string
+	// the offending token
+	Token string
+	// the index of the token in Args
+	Index int
+	// the label the token was given for, if any
+	Label string
+	// the label as shown in messages e.g. "--count", or "-n" for a single
+	// character label of a subcommand that clusters short flags; "--" and
+	// the label if empty
+	Flag string
+	// the type the label expects e.g. "int", if the label is known
+	Type string
+	// the underlying error e.g. from the strconv package
+	Err error
+	// a label the user may have meant, if the label is unknown
+	Suggestion string
+	// set if the label names a positional argument rather than a flag
+	Positional bool
+	// the suggestion as shown in messages, like Flag
+	suggestedFlag string
+	// the value given in the token, if it is not the token itself or the
+	// text after '=' e.g. "x" in '-nx'
+	value string
+}
+
+// Errors wrapped by a ParseError to describe what went wrong with a token.
+var (
+	// the label was given as the last token, without a value
+	ErrMissingValue = errors.New("missing value")
+	// the label is not used by the subcommand
+	ErrUnknownFlag = errors.New("unknown flag")
+	// the token is a positional value that the subcommand does not take
+	ErrUnexpectedArgument = errors.New("unexpected argument")
+)
+
+// Get the error message for a ParseError.
+func (e *ParseError) Error() string {
+	flag := e.Flag
+	if flag == "" {
+		flag = "--" + e.Label
+	}
+	if e.Err == ErrMissingValue {
+		return fmt.Sprintf("missing value for %s, expected %s", flag, e.Type)
+	}
+	if e.Err == ErrUnexpectedArgument {
+		return fmt.Sprintf("unexpected argument \"%s\"", e.Token)
+	}
+	if e.Err == ErrUnknownFlag {
+		if e.Suggestion != "" {
+			suggestion := e.suggestedFlag
+			if suggestion == "" {
+				suggestion = "--" + e.Suggestion
+			}
+			return fmt.Sprintf("unknown flag %s, did you mean %s?", flag, suggestion)
+		}
+		return fmt.Sprintf("unknown flag %s", flag)
+	}
+	reason := unwrapNumError(e.Err)
+	if e.Positional {
+		return fmt.Sprintf("invalid value \"%s\" for <%s>, expected %s: %s", e.Token, e.Label, e.Type, reason)
+	}
+	value := e.Token
+	if e.value != "" {
+		value = e.value
+	} else if i := strings.IndexByte(value, '='); i >= 0 && strings.HasPrefix(value, "-") {
+		value = value[i+1:]
+	}
+	return fmt.Sprintf("invalid value \"%s\" for %s, expected %s: %s", value, flag, e.Type, reason)
+}
+
+// Get the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Get a human-readable description of the error that echoes the command line
+// and points a caret at the offending token, e.g.
+//
+//	invalid value "abc" for --count, expected int: invalid syntax
+//	  app sub --count abc
+//	                  ^^^
+func (e *ParseError) Render() string {
+	line := ""
+	offset := 0
+	width := 1
+	for k, arg := range e.Args {
+		if k > 0 {
+			line = line + " "
+		}
+		if strings.ContainsAny(arg, " \t\"'") || len(arg) == 0 {
+			arg = strconv.Quote(arg)
+		}
+		if k == e.Index {
+			offset = len(line)
+			width = len(arg)
+		}
+		line = line + arg
+	}
+	if e.Index >= len(e.Args) {
+		// the token is missing, so point just past the end of the line
+		offset = len(line) + 1
+	}
+	if width == 0 {
+		width = 1
+	}
+	return fmt.Sprintf("%s\n  %s\n  %s%s", e.Error(), line, strings.Repeat(" ", offset), strings.Repeat("^", width))
+}
+
+// Get the error for a subcommand name that is not known, suggesting the
+// closest of the known names if there is one.
+func unknownSubcommandError(name string, known []string) error {
+	if s := suggest(name, known); s != "" {
+		return fmt.Errorf("unknown subcommand \"%s\", did you mean \"%s\"?", name, s)
+	}
+	return fmt.Errorf("unknown subcommand \"%s\"", name)
+}
+
+// Get the reason a value could not be converted, without the function name
+// and value that strconv adds to its errors.
+func unwrapNumError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package goldcmd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorRender(t *testing.T) {
+	cp := sampleCommandParser()
+	errs := cp.parseFlags([]string{"app", "sub", "-a", "abc", "-c", "x"})
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+	pe, ok := errs[0].(*ParseError)
+	if !ok {
+		t.Fatalf("expected a *ParseError, got %T", errs[0])
+	}
+	if pe.Token != "abc" || pe.Index != 3 || pe.Label != "a" || pe.Type != "int" {
+		t.Fatalf("unexpected parse error fields %+v", pe)
+	}
+	if !errors.Is(pe, strconv.ErrSyntax) {
+		t.Fatalf("parse error should wrap the strconv error")
+	}
+	want := "invalid value \"abc\" for --a, expected int: invalid syntax\n" +
+		"  app sub -a abc -c x\n" +
+		"             ^^^"
+	if pe.Render() != want {
+		t.Fatalf("unexpected rendering:\n%s\nwant:\n%s", pe.Render(), want)
+	}
+}
+
+func TestParseErrorMissingValue(t *testing.T) {
+	cp := sampleCommandParser()
+	errs := cp.parseFlags([]string{"app", "sub", "--d"})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrMissingValue) {
+		t.Fatalf("expected a missing value error, got %v", errs)
+	}
+	r := errs[0].(*ParseError).Render()
+	if !strings.HasSuffix(r, "  app sub --d\n              ^") {
+		t.Fatalf("the caret should point past the end of the line, got:\n%s", r)
+	}
+}
+
+func TestCliParse(t *testing.T) {
+	cli := sampleCli(t)
+	h, err := cli.Parse([]string{"app", "greet", "--name", "x", "--times=lots"})
+	if h == nil || h.name != "greet" {
+		t.Fatalf("the greet subcommand should be returned")
+	}
+	list, ok := err.(ErrorList)
+	if !ok || len(list) != 1 {
+		t.Fatalf("expected one error, got %v", err)
+	}
+	if pe, ok := list[0].(*ParseError); !ok || pe.Token != "--times=lots" || pe.Index != 4 {
+		t.Fatalf("unexpected error %v", list[0])
+	}
+	if _, err := cli.Parse([]string{"app", "wave"}); err == nil {
+		t.Fatalf("an unknown subcommand should be an error")
+	}
+}
@@ -1,6 +1,7 @@
 package src
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/GeorgeSaussy/goldcmd"
@@ -20,12 +21,21 @@ func Divider() *goldcmd.SubcommandHandler {
 	if err := ret.AddIntArg([]string{"second", "s"}, "second integer argument"); err != nil {
 		panic(err)
 	}
+	notZero := goldcmd.Check("not 0", func(value interface{}) error {
+		if value.(int) == 0 {
+			return errors.New("cannot divide by 0")
+		}
+		return nil
+	})
+	if err := ret.AddValidators("second", notZero); err != nil {
+		panic(err)
+	}
 	ret.Example("with mixed arguments", "calculator divide -f 1 -second 34", "0")
 	ret.Example("again with flags", "calculator divide -f=4 --second=2", "2")
 	ret.Handle(func(handler *goldcmd.SubcommandHandler) {
 		a, _ := handler.GetInt("f")
 		b, _ := handler.GetInt("s")
-		fmt.Printf("%d\n", a/b)
+		fmt.Fprintf(handler.Stdout(), "%d\n", a/b)
 	})
 	return ret
 }
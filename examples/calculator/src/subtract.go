@@ -23,7 +23,7 @@ func Subtracter() *goldcmd.SubcommandHandler {
 	ret.Handle(func(handler *goldcmd.SubcommandHandler) {
 		a, _ := handler.GetInt("f")
 		b, _ := handler.GetInt("s")
-		fmt.Printf("%d\n", a-b)
+		fmt.Fprintf(handler.Stdout(), "%d\n", a-b)
 	})
 	return ret
 }
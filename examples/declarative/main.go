@@ -0,0 +1,30 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/GeorgeSaussy/goldcmd"
+)
+
+//go:embed calculator.yaml
+var spec []byte
+
+func main() {
+	cli, err := goldcmd.NewCliFromYAML(spec, goldcmd.Handlers{
+		"add": func(handler *goldcmd.SubcommandHandler) {
+			a, _ := handler.GetInt("f")
+			b, _ := handler.GetInt("s")
+			fmt.Fprintf(handler.Stdout(), "%d\n", a+b)
+		},
+		"subtract": func(handler *goldcmd.SubcommandHandler) {
+			a, _ := handler.GetInt("f")
+			b, _ := handler.GetInt("s")
+			fmt.Fprintf(handler.Stdout(), "%d\n", a-b)
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	cli.Run()
+}
@@ -19,9 +19,10 @@ func handler() *goldcmd.SubcommandHandler {
 	ret.Example("Echo a string", "simpleecho echo --s \"example string\"", "example string")
 	ret.Handle(func(handler *goldcmd.SubcommandHandler) {
 		if a, err := handler.GetStr("s"); err != nil {
-			fmt.Printf("No string found!\n")
+			fmt.Fprintf(handler.Stderr(), "No string found!\n")
+			handler.SetExitCode(1)
 		} else {
-			fmt.Printf("%s\n", a)
+			fmt.Fprintf(handler.Stdout(), "%s\n", a)
 		}
 	})
 	return ret
@@ -1,3 +1,3 @@
 module github.com/GeorgeSaussy/goldcmd
 
-go 1.17
+go 1.18
@@ -0,0 +1,221 @@
+package goldcmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The kind of rule a group of parameters follows, named as in the spec.
+type groupKind string
+
+const (
+	// at most one of the parameters can be given
+	groupExclusive groupKind = "exclusive"
+	// exactly one of the parameters must be given
+	groupExactlyOne groupKind = "exactly_one"
+	// at least one of the parameters must be given
+	groupAtLeastOne groupKind = "at_least_one"
+	// either all of the parameters are given or none of them are
+	groupTogether groupKind = "together"
+)
+
+// A group of parameters of a subcommand and the rule they follow.
+type labelGroup struct {
+	kind groupKind
+	// the first alias of each parameter, in the order they were given
+	labels []string
+}
+
+// A Condition on the values of a subcommand, under which a parameter is
+// required, see RequiredIf.
+type Condition struct {
+	// the condition as shown in help and errors e.g. "--format=file", or
+	// what follows the flag of `label` e.g. "=file"
+	description string
+	// the label the condition is on, if it is shown as a flag
+	label string
+	test  func(h *SubcommandHandler) bool
+}
+
+// Get the condition as shown in help and errors, where the flag of its label
+// is named by a parser e.g. "-f=file" with short flags.
+func (c Condition) describe(cp *commandParser) string {
+	if c.label == "" {
+		return c.description
+	}
+	return cp.flagName(c.label) + c.description
+}
+
+// Get a Condition from a function of the parsed subcommand, with a
+// description for help and errors e.g. "--format is file".
+func When(description string, f func(h *SubcommandHandler) bool) Condition {
+	return Condition{description: description, test: f}
+}
+
+// Get a Condition that holds when a label is given, on the command line, in
+// the environment or in a configuration file.
+func WhenSet(label string) Condition {
+	return Condition{description: " is given", label: label, test: func(h *SubcommandHandler) bool {
+		return h.IsSet(label)
+	}}
+}
+
+// Get a Condition that holds when the value of a label or positional
+// argument, formatted as it would be given on the command line, is `value`.
+// The default counts, so a parameter can depend on an option the user has
+// left as it is.
+func WhenEquals(label string, value string) Condition {
+	return Condition{description: "=" + value, label: label, test: func(h *SubcommandHandler) bool {
+		for _, cp := range []*commandParser{h.argparser, h.paramparser, h.posparser} {
+			if v, ok := cp.valueOf(label); ok {
+				return v == value
+			}
+		}
+		return false
+	}}
+}
+
+// Join flag names for an error e.g. "--a, --b and --c", where the names are
+// labels of `cp`.
+func joinFlags(cp *commandParser, names []string, conjunction string) string {
+	flags := make([]string, 0, len(names))
+	for _, name := range names {
+		flags = append(flags, cp.flagName(name))
+	}
+	if len(flags) < 2 {
+		return strings.Join(flags, "")
+	}
+	return strings.Join(flags[:len(flags)-1], ", ") + " " + conjunction + " " + flags[len(flags)-1]
+}
+
+// Get the first alias of each of the labels of a new group, or an error if
+// one of them is not a parameter of the subcommand.
+func (h *SubcommandHandler) groupLabels(labels []string) ([]string, error) {
+	if len(labels) < 2 {
+		return nil, errors.New("a group needs at least two labels")
+	}
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if h.argparser.hasAlias(label) {
+			return nil, fmt.Errorf("the label \"%s\" is a required argument, so it cannot be in a group", label)
+		}
+		if !h.paramparser.hasAlias(label) {
+			return nil, fmt.Errorf("unknown label \"%s\"", label)
+		}
+		name := h.paramparser.aliasesOf(label)[0]
+		if strInList(name, names) {
+			return nil, fmt.Errorf("the label \"%s\" is repeated", label)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Add a group of parameters following a rule.
+func (h *SubcommandHandler) addGroup(kind groupKind, labels []string) error {
+	names, err := h.groupLabels(labels)
+	if err != nil {
+		return err
+	}
+	h.groups = append(h.groups, labelGroup{kind: kind, labels: names})
+	return nil
+}
+
+// Declare that at most one of the parameters can be given, e.g. '--json' and
+// '--yaml'. The usage line shows them as "[--json | --yaml]".
+func (h *SubcommandHandler) MutuallyExclusive(labels ...string) error {
+	return h.addGroup(groupExclusive, labels)
+}
+
+// Declare that exactly one of the parameters must be given, e.g. '--file'
+// or '--url'. The usage line shows them as "(--file <str> | --url <str>)".
+func (h *SubcommandHandler) ExactlyOneOf(labels ...string) error {
+	return h.addGroup(groupExactlyOne, labels)
+}
+
+// Declare that at least one of the parameters must be given.
+func (h *SubcommandHandler) AtLeastOneOf(labels ...string) error {
+	return h.addGroup(groupAtLeastOne, labels)
+}
+
+// Declare that the parameters must be given together, or not at all, e.g.
+// '--user' and '--password'.
+func (h *SubcommandHandler) RequiredTogether(labels ...string) error {
+	return h.addGroup(groupTogether, labels)
+}
+
+// Declare that a parameter must be given when a condition holds, e.g.
+//
+//	h.RequiredIf("output", WhenEquals("format", "file"))
+//
+// The condition is shown in help.
+func (h *SubcommandHandler) RequiredIf(label string, cond Condition) error {
+	if h.argparser.hasAlias(label) {
+		return fmt.Errorf("the label \"%s\" is already a required argument", label)
+	}
+	if !h.paramparser.hasAlias(label) {
+		return fmt.Errorf("unknown label \"%s\"", label)
+	}
+	if cond.test == nil {
+		return errors.New("invalid condition")
+	}
+	name := h.paramparser.aliasesOf(label)[0]
+	h.paramparser.conditions[name] = append(h.paramparser.conditions[name], cond)
+	return nil
+}
+
+// Get an error for every group or condition of the subcommand that the
+// parsed values do not follow.
+func (h *SubcommandHandler) checkGroups() []error {
+	errs := make([]error, 0)
+	for _, g := range h.groups {
+		given := make([]string, 0)
+		missing := make([]string, 0)
+		for _, name := range g.labels {
+			if h.IsSet(name) {
+				given = append(given, name)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+		switch {
+		case (g.kind == groupExclusive || g.kind == groupExactlyOne) && len(given) > 1:
+			errs = append(errs, fmt.Errorf("%s cannot be used together", joinFlags(h.paramparser, given, "and")))
+		case g.kind == groupExactlyOne && len(given) == 0:
+			errs = append(errs, fmt.Errorf("one of %s is required", joinFlags(h.paramparser, g.labels, "or")))
+		case g.kind == groupAtLeastOne && len(given) == 0:
+			errs = append(errs, fmt.Errorf("at least one of %s is required", joinFlags(h.paramparser, g.labels, "or")))
+		case g.kind == groupTogether && len(given) > 0 && len(missing) > 0:
+			errs = append(errs, fmt.Errorf("%s requires %s", joinFlags(h.paramparser, given, "and"), joinFlags(h.paramparser, missing, "and")))
+		}
+	}
+	for _, row := range h.paramparser.labelRows() {
+		for _, cond := range h.paramparser.conditions[row[0]] {
+			if !h.IsSet(row[0]) && cond.test(h) {
+				errs = append(errs, fmt.Errorf("%s is required when %s", h.paramparser.flagName(row[0]), cond.describe(h.paramparser)))
+			}
+		}
+	}
+	return errs
+}
+
+// Get the token for a group in a usage line e.g. "(--file <str> | --url
+// <str>)".
+func (h *SubcommandHandler) groupUsage(g labelGroup) string {
+	tokens := make([]string, 0, len(g.labels))
+	for _, name := range g.labels {
+		if h.paramparser.isBool(name) {
+			tokens = append(tokens, h.paramparser.flagName(name))
+		} else {
+			tokens = append(tokens, h.paramparser.usageToken(name))
+		}
+	}
+	switch g.kind {
+	case groupExactlyOne, groupAtLeastOne:
+		return "(" + strings.Join(tokens, " | ") + ")"
+	case groupExclusive:
+		return "[" + strings.Join(tokens, " | ") + "]"
+	}
+	return "[" + strings.Join(tokens, " ") + "]"
+}
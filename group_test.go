@@ -0,0 +1,113 @@
+package goldcmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleGroupCli(t *testing.T) Cli {
+	cli := NewCli("1.0", "A CLI with groups.")
+	sub, _ := NewSubcommandHandler("fetch", "Fetch a document.")
+	sub.AddStrParamWithDefault([]string{"file"}, "the file to read", "")
+	sub.AddStrParamWithDefault([]string{"url"}, "the URL to download", "")
+	sub.AddBoolParamWithDefault([]string{"json"}, "print JSON", false)
+	sub.AddBoolParamWithDefault([]string{"yaml"}, "print YAML", false)
+	sub.AddStrParamWithDefault([]string{"user", "u"}, "the user to log in as", "")
+	sub.AddStrParamWithDefault([]string{"password"}, "the password of the user", "")
+	sub.AddStrParamWithDefault([]string{"format"}, "where to write", "stdout")
+	sub.AddStrParamWithDefault([]string{"output", "o"}, "the file to write", "")
+	for _, err := range []error{
+		sub.ExactlyOneOf("file", "url"),
+		sub.MutuallyExclusive("json", "yaml"),
+		sub.RequiredTogether("u", "password"),
+		sub.RequiredIf("output", WhenEquals("format", "file")),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sub.Handle(func(h *SubcommandHandler) {})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func TestGroups(t *testing.T) {
+	cli := sampleGroupCli(t)
+	for _, args := range [][]string{
+		{"fetch", "--file", "a.txt"},
+		{"fetch", "--url", "https://example.com", "--json", "--user", "me", "--password", "secret"},
+		{"fetch", "--file", "a.txt", "--format", "file", "-o", "b.txt"},
+	} {
+		if code, _, errOut := execute(&cli, args...); code != 0 {
+			t.Fatalf("%v: unexpected error %q", args, errOut)
+		}
+	}
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"fetch"}, "error: one of --file or --url is required\n"},
+		{[]string{"fetch", "--file", "a", "--url", "b"}, "error: --file and --url cannot be used together\n"},
+		{[]string{"fetch", "--file", "a", "--json", "--yaml"}, "error: --json and --yaml cannot be used together\n"},
+		{[]string{"fetch", "--file", "a", "-u", "me"}, "error: --user requires --password\n"},
+		{[]string{"fetch", "--file", "a", "--format", "file"}, "error: --output is required when --format=file\n"},
+	}
+	for _, test := range tests {
+		if code, _, errOut := execute(&cli, test.args...); code != 2 || !strings.Contains(errOut, test.err) {
+			t.Fatalf("%v: expected %q, got %d %q", test.args, test.err, code, errOut)
+		}
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	sub, _ := NewSubcommandHandler("fetch", "Fetch a document.")
+	sub.AddStrArg([]string{"name"}, "a required argument")
+	sub.AddStrParamWithDefault([]string{"file"}, "the file to read", "")
+	sub.AddStrParamWithDefault([]string{"url"}, "the URL to download", "")
+	for _, err := range []error{
+		sub.MutuallyExclusive("file"),
+		sub.MutuallyExclusive("file", "name"),
+		sub.ExactlyOneOf("file", "missing"),
+		sub.AtLeastOneOf("file", "file"),
+		sub.RequiredIf("name", WhenSet("file")),
+		sub.RequiredIf("url", Condition{}),
+	} {
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+	}
+}
+
+func TestGroupHelp(t *testing.T) {
+	cli := sampleGroupCli(t)
+	_, out, _ := execute(&cli, "help", "fetch")
+	for _, want := range []string{
+		"usage: app fetch (--file <str> | --url <str>) [--json | --yaml] [--user <str> --password <str>] [OPTIONS]\n",
+		" --output, --o <str>\tthe file to write [required when --format=file]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+}
+
+func TestGroupSpec(t *testing.T) {
+	cli := sampleGroupCli(t)
+	spec := cli.Spec()
+	want := []GroupSpec{{"exactly_one", []string{"file", "url"}}, {"exclusive", []string{"json", "yaml"}},
+		{"together", []string{"user", "password"}}}
+	if !reflect.DeepEqual(spec.Subcommands[0].Groups, want) {
+		t.Fatalf("expected %+v, got %+v", want, spec.Subcommands[0].Groups)
+	}
+	var b bytes.Buffer
+	writeJSON(&b, spec)
+	loaded, err := NewCliFromJSON(b.Bytes(), Handlers{"fetch": func(h *SubcommandHandler) {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := loaded.Spec().Subcommands[0]; !reflect.DeepEqual(got.Groups, want) || got.Usage != spec.Subcommands[0].Usage {
+		t.Fatalf("the loaded CLI differs from the original: %+v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package goldcmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The number of values a list label takes: at least `min`, and at most `max`
+// if it is not negative.
+type valueCount struct {
+	min int
+	max int
+}
+
+// Split a value given for a list label on the separator of the label, if it
+// has one.
+func (cp *commandParser) splitValue(alias string, value string) []string {
+	if sep, ok := cp.separators[cp.aliasesOf(alias)[0]]; ok && sep != "" {
+		return strings.Split(value, sep)
+	}
+	return []string{value}
+}
+
+// Join the values of a list label with its separator, or with commas if it
+// does not have one.
+func (cp *commandParser) joinValues(alias string, values []string) string {
+	if sep, ok := cp.separators[cp.aliasesOf(alias)[0]]; ok && sep != "" {
+		return strings.Join(values, sep)
+	}
+	return strings.Join(values, ",")
+}
+
+// Format a list of integers as they would be given on the command line.
+func intStrings(values []int) []string {
+	ret := make([]string, 0, len(values))
+	for _, value := range values {
+		ret = append(ret, strconv.Itoa(value))
+	}
+	return ret
+}
+
+// Format a list of floats as they would be given on the command line.
+func floatStrings(values []float64) []string {
+	ret := make([]string, 0, len(values))
+	for _, value := range values {
+		ret = append(ret, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	return ret
+}
+
+// Use the values given for a list label in one place. Values given on the
+// command line are added to the ones before them, while values from anywhere
+// else replace the current values, so the command line replaces the
+// environment, which replaces the configuration files and the default.
+// If one of the values cannot be converted, the label is not changed.
+func (cp *commandParser) useItems(alias string, items []string) error {
+	l := cp.labelOf(alias)
+	list, ok := l.value.(listValue)
+	if !ok {
+		return fmt.Errorf("the label \"%s\" is not a list", alias)
+	}
+	return list.setItems(items, cp.sources[l.aliases[0]].Kind == SourceCommandLine)
+}
+
+// Get the number of values of the label an alias belongs to, or -1 if it is
+// not a list label with a value.
+func (cp *commandParser) listLen(alias string) int {
+	l := cp.labelOf(alias)
+	if l == nil || !cp.isSet(alias) {
+		return -1
+	}
+	if list, ok := l.value.(listValue); ok {
+		return len(list.items())
+	}
+	return -1
+}
+
+// Get an error for every list label with too few or too many values.
+func (cp *commandParser) checkCounts() []error {
+	errs := make([]error, 0)
+	for _, row := range cp.labelRows() {
+		count, ok := cp.counts[row[0]]
+		n := cp.listLen(row[0])
+		if !ok || n < 0 {
+			continue
+		}
+		if n < count.min {
+			errs = append(errs, fmt.Errorf("expected at least %d values for %s, got %d", count.min, cp.flagName(row[0]), n))
+		} else if count.max >= 0 && n > count.max {
+			errs = append(errs, fmt.Errorf("expected at most %d values for %s, got %d", count.max, cp.flagName(row[0]), n))
+		}
+	}
+	return errs
+}
+
+// Add a string list argument to the subcommand. The flag can be given
+// several times, and every value is kept e.g. '--tag a --tag b'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddStrsArg(aliases []string, doc string) error {
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.argparser.addLabel(aliases, doc, &strsValue{})
+	return nil
+}
+
+// Add an integer list argument to the subcommand. The flag can be given
+// several times, and every value is kept e.g. '--port 80 --port 443'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddIntsArg(aliases []string, doc string) error {
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.argparser.addLabel(aliases, doc, &intsValue{})
+	return nil
+}
+
+// Add a float list argument to the subcommand. The flag can be given
+// several times, and every value is kept e.g. '--weight 0.5 --weight 2'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddFloatsArg(aliases []string, doc string) error {
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.argparser.addLabel(aliases, doc, &floatsValue{})
+	return nil
+}
+
+// Add a string list parameter to the subcommand with a default value, which
+// is replaced if the flag is given.
+func (h *SubcommandHandler) AddStrsParamWithDefault(aliases []string, doc string, deflt []string) error {
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.paramparser.addLabelWithDefault(aliases, doc, &strsValue{values: append([]string{}, deflt...)})
+	return nil
+}
+
+// Add an integer list parameter to the subcommand with a default value,
+// which is replaced if the flag is given.
+func (h *SubcommandHandler) AddIntsParamWithDefault(aliases []string, doc string, deflt []int) error {
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.paramparser.addLabelWithDefault(aliases, doc, &intsValue{values: append([]int{}, deflt...)})
+	return nil
+}
+
+// Add a float list parameter to the subcommand with a default value, which
+// is replaced if the flag is given.
+func (h *SubcommandHandler) AddFloatsParamWithDefault(aliases []string, doc string, deflt []float64) error {
+	if !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.paramparser.addLabelWithDefault(aliases, doc, &floatsValue{values: append([]float64{}, deflt...)})
+	return nil
+}
+
+// Get the parser of a list label of the subcommand, or an error if the label
+// is not known or is not a list.
+func (h *SubcommandHandler) listParser(label string) (*commandParser, error) {
+	cp := parserForAlias(label, h.parsers())
+	if cp == nil {
+		return nil, fmt.Errorf("unknown label \"%s\"", label)
+	}
+	if _, ok := cp.labelOf(label).value.(listValue); !ok {
+		return nil, fmt.Errorf("the label \"%s\" is not a list", label)
+	}
+	return cp, nil
+}
+
+// Split every value given for a list label on a separator, so that e.g.
+// '--include x,y' is the same as '--include x --include y'. Values from the
+// environment and configuration files are split too.
+func (h *SubcommandHandler) SetSeparator(label string, sep string) error {
+	cp, err := h.listParser(label)
+	if err != nil {
+		return err
+	}
+	if sep == "" {
+		return errors.New("the separator cannot be empty")
+	}
+	cp.separators[cp.aliasesOf(label)[0]] = sep
+	return nil
+}
+
+// Set the number of values a list label takes: at least `min`, and at most
+// `max` if it is not negative. The subcommand fails without running its
+// handler function if the count is not met, including by the default.
+func (h *SubcommandHandler) SetCount(label string, min int, max int) error {
+	cp, err := h.listParser(label)
+	if err != nil {
+		return err
+	}
+	if min < 0 || (max >= 0 && max < min) {
+		return errors.New("invalid value count")
+	}
+	cp.counts[cp.aliasesOf(label)[0]] = valueCount{min: min, max: max}
+	return nil
+}
+
+// Get the values of an integer list argument or parameter.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetInts(key string) ([]int, error) {
+	if val, b := h.get(key).([]int); b {
+		return val, nil
+	}
+	return nil, errors.New("key not available")
+}
+
+// Get the values of a float list argument or parameter.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetFloats(key string) ([]float64, error) {
+	if val, b := h.get(key).([]float64); b {
+		return val, nil
+	}
+	return nil, errors.New("key not available")
+}
@@ -0,0 +1,143 @@
+package goldcmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleListCli(t *testing.T, env map[string]string) Cli {
+	cli := NewCli("1.0", "A CLI with list flags.")
+	sub, _ := NewSubcommandHandler("build", "Build some targets.")
+	sub.AddStrsArg([]string{"target", "t"}, "a target to build")
+	sub.AddIntsParamWithDefault([]string{"port"}, "a port to listen on", []int{80})
+	sub.AddFloatsParamWithDefault([]string{"weight"}, "a weight", nil)
+	sub.AddStrsParamWithDefault([]string{"include"}, "the paths to include", []string{"src"})
+	if err := sub.SetSeparator("include", ","); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := sub.SetCount("target", 1, 3); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sub.BindEnv("port", "PORTS")
+	sub.Handle(func(h *SubcommandHandler) {
+		targets, _ := h.GetStrs("t")
+		ports, _ := h.GetInts("port")
+		weights, _ := h.GetFloats("weight")
+		include, _ := h.GetStrs("include")
+		fmt.Fprintf(h.Stdout(), "%v %v %v %v", targets, ports, weights, include)
+	})
+	cli.HandleSubcommand(sub)
+	cli.SetLookupEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	return cli
+}
+
+func TestListFlags(t *testing.T) {
+	env := make(map[string]string)
+	cli := sampleListCli(t, env)
+	tests := []struct {
+		args []string
+		out  string
+	}{
+		{[]string{"build", "-t", "a"}, "[a] [80] [] [src]"},
+		{[]string{"build", "-t", "a", "--target=b", "--port", "1", "--port", "2", "--weight", "0.5"}, "[a b] [1 2] [0.5] [src]"},
+		{[]string{"build", "-t", "a", "--include", "x,y", "--include", "z"}, "[a] [80] [] [x y z]"},
+	}
+	for _, test := range tests {
+		if code, out, errOut := execute(&cli, test.args...); code != 0 || out != test.out {
+			t.Fatalf("%v: expected %q, got %d %q %q", test.args, test.out, code, out, errOut)
+		}
+	}
+	env["PORTS"] = "8080"
+	if _, out, _ := execute(&cli, "build", "-t", "a"); out != "[a] [8080] [] [src]" {
+		t.Fatalf("the environment should replace the default, got %q", out)
+	}
+	if _, out, _ := execute(&cli, "build", "-t", "a", "--port", "1", "--port", "2"); out != "[a] [1 2] [] [src]" {
+		t.Fatalf("the command line should replace the environment, got %q", out)
+	}
+	sub := cli.subcommands[0]
+	if source, _ := sub.Source("port"); source.Kind != SourceCommandLine || source.Index != 6 {
+		t.Fatalf("the source should be the last value given, got %+v", source)
+	}
+}
+
+func TestListFlagErrors(t *testing.T) {
+	cli := sampleListCli(t, nil)
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"build"}, "missing required argument --target"},
+		{[]string{"build", "-t", "a", "-t", "b", "-t", "c", "-t", "d"}, "expected at most 3 values for --target, got 4"},
+		{[]string{"build", "-t", "a", "--port", "x"}, "invalid value \"x\" for --port, expected []int: invalid syntax"},
+	}
+	for _, test := range tests {
+		if code, _, errOut := execute(&cli, test.args...); code != 2 || !strings.Contains(errOut, test.err) {
+			t.Fatalf("%v: expected error %q, got %q", test.args, test.err, errOut)
+		}
+	}
+	sub := cli.subcommands[0]
+	if err := sub.SetSeparator("nope", ","); err == nil {
+		t.Fatalf("expected an error for an unknown label")
+	}
+	sub.AddIntParamWithDefault([]string{"jobs"}, "the number of jobs", 1)
+	if err := sub.SetCount("jobs", 0, 1); err == nil {
+		t.Fatalf("expected an error for a label that is not a list")
+	}
+	if err := sub.SetCount("target", 2, 1); err == nil {
+		t.Fatalf("expected an error for an invalid count")
+	}
+}
+
+func TestListFlagHelp(t *testing.T) {
+	cli := sampleListCli(t, nil)
+	_, out, _ := execute(&cli, "help", "build")
+	for _, want := range []string{
+		"usage: app build --target <str>... [OPTIONS]",
+		" --target, --t <str>\ta target to build [repeatable]\n",
+		" --include <str>\tthe paths to include [repeatable, split on \",\"]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+}
+
+func TestListFlagConfig(t *testing.T) {
+	dir := t.TempDir()
+	cli := sampleListCli(t, nil)
+	cli.SetConfigFiles(ConfigFiles{})
+	path := filepath.Join(dir, "build.json")
+	writeConfig(t, path, `{"build": {"port": [1, 2], "include": "a,b"}}`)
+	if _, out, errOut := execute(&cli, "--config", path, "build", "-t", "x"); out != "[x] [1 2] [] [a b]" {
+		t.Fatalf("unexpected output %q %q", out, errOut)
+	}
+	writeConfig(t, path, "{\n  \"build\": {\n    \"port\": [1, \"two\"]\n  }\n}")
+	if _, _, errOut := execute(&cli, "--config", path, "build", "-t", "x"); !strings.Contains(errOut, path+`:3: invalid value "two" for build.port, expected []int: invalid syntax`) {
+		t.Fatalf("unexpected error %q", errOut)
+	}
+}
+
+func TestListFlagSpec(t *testing.T) {
+	cli := sampleListCli(t, nil)
+	spec := cli.Spec()
+	labels := spec.Subcommands[0].Labels
+	if labels[0].Type != "[]str" || labels[0].Min != 1 || labels[0].Max != 3 || labels[3].Separator != "," {
+		t.Fatalf("unexpected labels %+v", labels)
+	}
+	var b bytes.Buffer
+	writeJSON(&b, spec)
+	loaded, err := NewCliFromJSON(b.Bytes(), Handlers{"build": func(h *SubcommandHandler) {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.Spec(), spec) {
+		t.Fatalf("the loaded CLI differs from the original:\n%+v\n%+v", loaded.Spec(), spec)
+	}
+}
@@ -0,0 +1,786 @@
+package goldcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The handler functions of a CLI built from a specification, by name.
+//
+// A subcommand of the specification is bound to the handler named by its
+// "handler" field or, if it has none, to the handler named after its command
+// words e.g. "db migrate".
+type Handlers map[string]func(h *SubcommandHandler)
+
+// Build a Cli from a JSON specification. The specification has the form of
+// Spec, so the output of 'help --json' can be loaded, except that the fields
+// only derived from the others, like "usage", are ignored, and so are the
+// built-in subcommands if they are unchanged. Any other subcommand with a
+// reserved name, like "help" or "version", is an error. A subcommand may name
+// its handler with a "handler" field. For example:
+//
+//	{
+//	  "version": "1.0",
+//	  "documentation": "A simple calculator CLI app.",
+//	  "subcommands": [{
+//	    "name": "add",
+//	    "documentation": "Add two numbers together.",
+//	    "labels": [
+//	      {"aliases": ["first", "f"], "type": "int", "required": true, "documentation": "first integer argument"},
+//	      {"aliases": ["second", "s"], "type": "int", "default": 0, "documentation": "second integer argument"}
+//	    ]
+//	  }]
+//	}
+//
+// Every problem with the specification is reported, each with the path of the
+// offending value e.g. "subcommands[0].labels[1].type". The loaded Cli is
+// then checked with Validate.
+func NewCliFromJSON(data []byte, handlers Handlers) (Cli, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var spec interface{}
+	if err := dec.Decode(&spec); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			line := 1 + bytes.Count(data[:syntaxErr.Offset], []byte("\n"))
+			return Cli{}, fmt.Errorf("line %d: %s", line, err)
+		}
+		return Cli{}, err
+	}
+	return newCliFromSpec(spec, handlers)
+}
+
+// Build a Cli from a YAML specification, with the same fields as the JSON
+// specification of NewCliFromJSON. Only a subset of YAML is supported: block
+// mappings and sequences, flow sequences of scalars e.g. "[first, f]",
+// scalars, and comments.
+func NewCliFromYAML(data []byte, handlers Handlers) (Cli, error) {
+	spec, err := parseYAML(data)
+	if err != nil {
+		return Cli{}, err
+	}
+	return newCliFromSpec(spec, handlers)
+}
+
+// Build a Cli from a specification file, which is read as YAML if its name
+// ends with ".yaml" or ".yml", and as JSON otherwise. Errors are prefixed with
+// the name of the file.
+func NewCliFromFile(path string, handlers Handlers) (Cli, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Cli{}, err
+	}
+	var cli Cli
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		cli, err = NewCliFromYAML(data, handlers)
+	default:
+		cli, err = NewCliFromJSON(data, handlers)
+	}
+	if list, ok := err.(ErrorList); ok {
+		for k, e := range list {
+			list[k] = fmt.Errorf("%s: %w", path, e)
+		}
+	} else if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return cli, err
+}
+
+// The state of building a Cli from a specification.
+type specLoader struct {
+	handlers Handlers
+	// the names of the handlers bound to a subcommand
+	used map[string]bool
+	errs ErrorList
+}
+
+// Record an error about the value at `path`.
+func (l *specLoader) errorf(path string, format string, a ...interface{}) {
+	if path == "" {
+		path = "specification"
+	}
+	l.errs = append(l.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, a...)))
+}
+
+// Get the path of a field of the object at `path`.
+func fieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Get the JSON type name of a value, for error messages.
+func specTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a Boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Get the object at `path`, checking that it only has the fields in `known`.
+// The second return value is false if the value is not an object.
+func (l *specLoader) object(path string, v interface{}, known ...string) (map[string]interface{}, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		l.errorf(path, "expected an object, got %s", specTypeName(v))
+		return nil, false
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strInList(key, known) {
+			if s := suggest(key, known); s != "" {
+				l.errorf(fieldPath(path, key), "unknown field, did you mean %q?", s)
+			} else {
+				l.errorf(fieldPath(path, key), "unknown field")
+			}
+		}
+	}
+	return obj, true
+}
+
+// Return true if a string is in a list.
+func strInList(s string, l []string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Get a string field of an object, or an empty string if it is not set.
+func (l *specLoader) str(path string, obj map[string]interface{}, key string) string {
+	v, ok := obj[key]
+	if !ok || v == nil {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		l.errorf(fieldPath(path, key), "expected a string, got %s", specTypeName(v))
+	}
+	return s
+}
+
+// Get a Boolean field of an object, or false if it is not set.
+func (l *specLoader) boolean(path string, obj map[string]interface{}, key string) bool {
+	v, ok := obj[key]
+	if !ok || v == nil {
+		return false
+	}
+	b, ok := v.(bool)
+	if !ok {
+		l.errorf(fieldPath(path, key), "expected true or false, got %s", specTypeName(v))
+	}
+	return b
+}
+
+// Get an integer field of an object, or `deflt` if it is not set.
+func (l *specLoader) integer(path string, obj map[string]interface{}, key string, deflt int) int {
+	v, ok := obj[key]
+	if !ok || v == nil {
+		return deflt
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		l.errorf(fieldPath(path, key), "expected an integer, got %s", specTypeName(v))
+		return deflt
+	}
+	i, err := n.Int64()
+	if err != nil {
+		l.errorf(fieldPath(path, key), "expected an integer, got %s", n)
+		return deflt
+	}
+	return int(i)
+}
+
+// Get a number field of an object, or 0 if it is not set.
+func (l *specLoader) number(path string, obj map[string]interface{}, key string) float64 {
+	v, ok := obj[key]
+	if !ok || v == nil {
+		return 0
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		l.errorf(fieldPath(path, key), "expected a number, got %s", specTypeName(v))
+		return 0
+	}
+	f, err := n.Float64()
+	if err != nil {
+		l.errorf(fieldPath(path, key), "expected a number, got %s", n)
+	}
+	return f
+}
+
+// Get a list field of an object, or nil if it is not set.
+func (l *specLoader) list(path string, obj map[string]interface{}, key string) []interface{} {
+	v, ok := obj[key]
+	if !ok || v == nil {
+		return nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		l.errorf(fieldPath(path, key), "expected a list, got %s", specTypeName(v))
+	}
+	return items
+}
+
+// Get the value at `path` as a value of the type named `typeName`. The second
+// return value is false if it has another type.
+func (l *specLoader) value(path string, v interface{}, typeName string) (interface{}, bool) {
+	switch typeName {
+	case "int":
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return int(i), true
+			}
+		}
+	case "float":
+		if n, ok := v.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return f, true
+			}
+		}
+	case "str":
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+	case "bool":
+		if b, ok := v.(bool); ok {
+			return b, true
+		}
+	case "[]int", "[]str", "[]float":
+		items, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		values := map[string]interface{}{"[]int": []int{}, "[]str": []string{}, "[]float": []float64{}}[typeName]
+		for k, item := range items {
+			value, ok := l.value(fmt.Sprintf("%s[%d]", path, k), item, typeName[2:])
+			if !ok {
+				return nil, false
+			}
+			switch typeName {
+			case "[]int":
+				values = append(values.([]int), value.(int))
+			case "[]str":
+				values = append(values.([]string), value.(string))
+			case "[]float":
+				values = append(values.([]float64), value.(float64))
+			}
+		}
+		return values, true
+	}
+	l.errorf(path, "expected a value of type %s, got %s", typeName, specTypeName(v))
+	return nil, false
+}
+
+// The type names that can be used in a specification.
+var specTypes = []string{"int", "str", "float", "bool", "[]int", "[]str", "[]float",
+	"duration", "time", "url", "ip", "ip:port", "cidr", "size", "regexp", "choice"}
+
+// Get the choices of a flag or positional argument of type "choice", each
+// given as a string or as an object with a value and its documentation.
+func (l *specLoader) choices(path string, obj map[string]interface{}) ([]string, map[string]string) {
+	choices := make([]string, 0)
+	docs := make(map[string]string)
+	for k, v := range l.list(path, obj, "choices") {
+		choicePath := fmt.Sprintf("%s.choices[%d]", path, k)
+		if s, ok := v.(string); ok {
+			choices = append(choices, s)
+		} else if choice, ok := l.object(choicePath, v, "value", "documentation"); ok {
+			value := l.str(choicePath, choice, "value")
+			choices = append(choices, value)
+			if doc := l.str(choicePath, choice, "documentation"); doc != "" {
+				docs[value] = doc
+			}
+		}
+	}
+	return choices, docs
+}
+
+// Set the options of the choices of a flag or positional argument of type
+// "choice" once it has been added to a subcommand.
+func (l *specLoader) choiceOptions(path string, obj map[string]interface{}, docs map[string]string, h *SubcommandHandler, label string) {
+	if err := h.SetIgnoreCase(label, l.boolean(path, obj, "ignore_case")); err != nil {
+		l.errorf(fieldPath(path, "ignore_case"), "%s", err)
+	}
+	for choice, doc := range docs {
+		h.DescribeChoice(label, choice, doc)
+	}
+}
+
+// Add the validators of a flag or positional argument once it has been added
+// to a subcommand. The description of a rule is not read, since it follows
+// from the rule.
+func (l *specLoader) validators(path string, obj map[string]interface{}, h *SubcommandHandler, label string) {
+	numbers := map[string]func(bound float64) Validator{"at_least": AtLeast, "at_most": AtMost,
+		"greater_than": GreaterThan, "less_than": LessThan}
+	lengths := map[string]func(length int) Validator{"min_length": MinLength, "max_length": MaxLength}
+	validators := make([]Validator, 0)
+	for k, v := range l.list(path, obj, "validators") {
+		validatorPath := fmt.Sprintf("%s.validators[%d]", path, k)
+		spec, ok := l.object(validatorPath, v, "rule", "value", "pattern", "description")
+		if !ok {
+			continue
+		}
+		rule := l.str(validatorPath, spec, "rule")
+		if f, ok := numbers[rule]; ok {
+			validators = append(validators, f(l.number(validatorPath, spec, "value")))
+		} else if f, ok := lengths[rule]; ok {
+			validators = append(validators, f(l.integer(validatorPath, spec, "value", 0)))
+		} else if rule == "matches" {
+			re, err := regexp.Compile(l.str(validatorPath, spec, "pattern"))
+			if err != nil {
+				l.errorf(fieldPath(validatorPath, "pattern"), "%s", err)
+				continue
+			}
+			validators = append(validators, Matches(re))
+		} else if rule == "check" {
+			l.errorf(fieldPath(validatorPath, "rule"), "a rule made with Check cannot be loaded, add it to the subcommand in code")
+		} else {
+			l.errorf(fieldPath(validatorPath, "rule"), "unknown rule %q, expected one of at_least, at_most, greater_than, less_than, min_length, max_length, matches", rule)
+		}
+	}
+	if len(validators) > 0 {
+		if err := h.AddValidators(label, validators...); err != nil {
+			l.errorf(fieldPath(path, "validators"), "%s", err)
+		}
+	}
+}
+
+// Get the type name field of an object, checking that it is supported.
+func (l *specLoader) typeName(path string, obj map[string]interface{}) string {
+	t := l.str(path, obj, "type")
+	if t == "" {
+		l.errorf(fieldPath(path, "type"), "missing type, expected one of %s", strings.Join(specTypes, ", "))
+	} else if !strInList(t, specTypes) {
+		l.errorf(fieldPath(path, "type"), "unknown type %q, expected one of %s", t, strings.Join(specTypes, ", "))
+		return ""
+	}
+	return t
+}
+
+// Build a Cli from a decoded specification.
+func newCliFromSpec(spec interface{}, handlers Handlers) (Cli, error) {
+	l := &specLoader{handlers: handlers, used: make(map[string]bool)}
+	obj, ok := l.object("", spec, "spec_version", "name", "version", "documentation", "subcommands")
+	if !ok {
+		return Cli{}, l.errs
+	}
+	if version := l.integer("", obj, "spec_version", SpecVersion); version > SpecVersion {
+		l.errorf("spec_version", "unsupported version %d, expected at most %d", version, SpecVersion)
+	}
+	cli := NewCli(l.str("", obj, "version"), l.str("", obj, "documentation"))
+	if name := l.str("", obj, "name"); name != "" {
+		cli.SetName(name)
+	}
+	// the specs of the built-in subcommands, which are in the output of
+	// 'help --json' and are skipped if they are unchanged
+	builtins := make(map[string]interface{})
+	for _, builtin := range cli.spec(cli.progName(nil)).Subcommands {
+		builtins[builtin.Name] = normalizeSpec(builtin)
+	}
+	for k, v := range l.list("", obj, "subcommands") {
+		if sub, ok := v.(map[string]interface{}); ok {
+			name, _ := sub["name"].(string)
+			if builtin, ok := builtins[name]; ok && reflect.DeepEqual(normalizeSpec(v), builtin) {
+				continue
+			}
+			if _, ok := builtins[name]; ok || name == "help" || name == "__complete" {
+				l.errorf(fmt.Sprintf("subcommands[%d].name", k), "the name %q is reserved", name)
+				continue
+			}
+		}
+		if subcmd := l.subcommand(fmt.Sprintf("subcommands[%d]", k), v, nil); subcmd != nil {
+			cli.HandleSubcommand(subcmd)
+		}
+	}
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !l.used[name] {
+			l.errs = append(l.errs, fmt.Errorf("the handler %q is not used by the specification", name))
+		}
+	}
+	if len(l.errs) > 0 {
+		return Cli{}, l.errs
+	}
+	if err := cli.Validate(); err != nil {
+		return Cli{}, err
+	}
+	return cli, nil
+}
+
+// Get a spec, or a part of one, in the form it has when it is decoded from
+// JSON, so specs can be compared whatever they were built from.
+func normalizeSpec(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var ret interface{}
+	if err := dec.Decode(&ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+// Build the subcommand described at `path`, where `words` are the command
+// words of its parent. It returns nil if the subcommand could not be created.
+func (l *specLoader) subcommand(path string, v interface{}, words []string) *SubcommandHandler {
+	obj, ok := l.object(path, v, "name", "documentation", "handler", "hidden", "cluster_short_flags", "labels", "positionals", "groups", "examples", "subcommands", "path", "usage", "runnable")
+	if !ok {
+		return nil
+	}
+	name := l.str(path, obj, "name")
+	h, err := NewSubcommandHandler(name, l.str(path, obj, "documentation"))
+	if err != nil {
+		l.errorf(fieldPath(path, "name"), "%s", err)
+		return nil
+	}
+	words = append(append([]string{}, words...), name)
+	h.SetHidden(l.boolean(path, obj, "hidden"))
+	h.ClusterShortFlags(l.boolean(path, obj, "cluster_short_flags"))
+	for k, v := range l.list(path, obj, "labels") {
+		l.label(fmt.Sprintf("%s.labels[%d]", path, k), v, h)
+	}
+	for k, v := range l.list(path, obj, "positionals") {
+		l.positional(fmt.Sprintf("%s.positionals[%d]", path, k), v, h)
+	}
+	for k, v := range l.list(path, obj, "groups") {
+		l.group(fmt.Sprintf("%s.groups[%d]", path, k), v, h)
+	}
+	for k, v := range l.list(path, obj, "examples") {
+		examplePath := fmt.Sprintf("%s.examples[%d]", path, k)
+		if ex, ok := l.object(examplePath, v, "documentation", "command", "output"); ok {
+			h.Example(l.str(examplePath, ex, "documentation"), l.str(examplePath, ex, "command"), l.str(examplePath, ex, "output"))
+		}
+	}
+	children := l.list(path, obj, "subcommands")
+	for k, v := range children {
+		if child := l.subcommand(fmt.Sprintf("%s.subcommands[%d]", path, k), v, words); child != nil {
+			h.HandleSubcommand(child)
+		}
+	}
+	handlerName := l.str(path, obj, "handler")
+	if handlerName == "" {
+		handlerName = strings.Join(words, " ")
+	}
+	if f, ok := l.handlers[handlerName]; ok {
+		h.Handle(f)
+		l.used[handlerName] = true
+	} else if _, named := obj["handler"]; named || len(children) == 0 {
+		known := make([]string, 0, len(l.handlers))
+		for name := range l.handlers {
+			known = append(known, name)
+		}
+		if s := suggest(handlerName, known); s != "" {
+			l.errorf(path, "no handler named %q, did you mean %q?", handlerName, s)
+		} else {
+			l.errorf(path, "no handler named %q", handlerName)
+		}
+	}
+	return h
+}
+
+// Add the flag described at `path` to a subcommand.
+func (l *specLoader) label(path string, v interface{}, h *SubcommandHandler) {
+	obj, ok := l.object(path, v, "name", "aliases", "type", "required", "default", "choices", "ignore_case", "layouts", "separator", "min", "max", "env", "validators", "documentation")
+	if !ok {
+		return
+	}
+	aliases := make([]string, 0)
+	if name := l.str(path, obj, "name"); name != "" {
+		aliases = append(aliases, name)
+	}
+	for k, alias := range l.list(path, obj, "aliases") {
+		s, ok := alias.(string)
+		if !ok {
+			l.errorf(fmt.Sprintf("%s.aliases[%d]", path, k), "expected a string, got %s", specTypeName(alias))
+		} else if !strInList(s, aliases) {
+			aliases = append(aliases, s)
+		}
+	}
+	if len(aliases) == 0 {
+		l.errorf(path, "missing name or aliases")
+		return
+	}
+	doc := l.str(path, obj, "documentation")
+	required := l.boolean(path, obj, "required")
+	t := l.typeName(path, obj)
+	deflt, hasDefault := obj["default"]
+	if required && hasDefault {
+		l.errorf(fieldPath(path, "default"), "a required flag cannot have a default")
+		return
+	}
+	if t == "" {
+		return
+	}
+	layouts := make([]string, 0)
+	for k, layout := range l.list(path, obj, "layouts") {
+		if s, ok := layout.(string); ok {
+			layouts = append(layouts, s)
+		} else {
+			l.errorf(fmt.Sprintf("%s.layouts[%d]", path, k), "expected a string, got %s", specTypeName(layout))
+		}
+	}
+	_, hasChoices := obj["choices"]
+	if hasChoices != (t == "choice") {
+		l.errorf(fieldPath(path, "choices"), "only a flag of type choice has choices")
+		return
+	}
+	choices, docs := l.choices(path, obj)
+	var err error
+	if t == "choice" {
+		if required {
+			err = h.AddChoiceArg(aliases, doc, choices)
+		} else {
+			value := interface{}("")
+			if len(choices) > 0 {
+				value = choices[0]
+			}
+			if hasDefault {
+				if value, ok = l.value(fieldPath(path, "default"), deflt, "str"); !ok {
+					return
+				}
+			}
+			err = h.AddChoiceParamWithDefault(aliases, doc, choices, value.(string))
+		}
+	} else if value := newRichValue(t, layouts); value != nil {
+		// the default of these types is given as text, which may be empty
+		// for no value e.g. a nil URL
+		if hasDefault {
+			s, ok := l.value(fieldPath(path, "default"), deflt, "str")
+			if !ok {
+				return
+			}
+			if s != "" {
+				if err := value.Set(s.(string)); err != nil {
+					l.errorf(fieldPath(path, "default"), "%s", err)
+					return
+				}
+			}
+		}
+		if required {
+			err = h.AddValueArg(aliases, doc, value)
+		} else {
+			err = h.AddValueParam(aliases, doc, value)
+		}
+	} else if required {
+		switch t {
+		case "int":
+			err = h.AddIntArg(aliases, doc)
+		case "str":
+			err = h.AddStrArg(aliases, doc)
+		case "float":
+			err = h.AddFloatArg(aliases, doc)
+		case "bool":
+			err = h.AddBoolArg(aliases, doc)
+		case "[]int":
+			err = h.AddIntsArg(aliases, doc)
+		case "[]str":
+			err = h.AddStrsArg(aliases, doc)
+		case "[]float":
+			err = h.AddFloatsArg(aliases, doc)
+		}
+	} else {
+		value := map[string]interface{}{"int": 0, "str": "", "float": 0.0, "bool": false,
+			"[]int": []int{}, "[]str": []string{}, "[]float": []float64{}}[t]
+		if hasDefault {
+			if value, ok = l.value(fieldPath(path, "default"), deflt, t); !ok {
+				return
+			}
+		}
+		switch t {
+		case "int":
+			err = h.AddIntParamWithDefault(aliases, doc, value.(int))
+		case "str":
+			err = h.AddStrParamWithDefault(aliases, doc, value.(string))
+		case "float":
+			err = h.AddFloatParamWithDefault(aliases, doc, value.(float64))
+		case "bool":
+			err = h.AddBoolParamWithDefault(aliases, doc, value.(bool))
+		case "[]int":
+			err = h.AddIntsParamWithDefault(aliases, doc, value.([]int))
+		case "[]str":
+			err = h.AddStrsParamWithDefault(aliases, doc, value.([]string))
+		case "[]float":
+			err = h.AddFloatsParamWithDefault(aliases, doc, value.([]float64))
+		}
+	}
+	if err != nil {
+		l.errorf(path, "%s", err)
+		return
+	}
+	if t == "choice" {
+		l.choiceOptions(path, obj, docs, h, aliases[0])
+	}
+	if sep := l.str(path, obj, "separator"); sep != "" {
+		if err := h.SetSeparator(aliases[0], sep); err != nil {
+			l.errorf(fieldPath(path, "separator"), "%s", err)
+		}
+	}
+	_, hasMin := obj["min"]
+	_, hasMax := obj["max"]
+	if hasMin || hasMax {
+		if err := h.SetCount(aliases[0], l.integer(path, obj, "min", 0), l.integer(path, obj, "max", -1)); err != nil {
+			l.errorf(path, "%s", err)
+		}
+	}
+	names := make([]string, 0)
+	for k, name := range l.list(path, obj, "env") {
+		if s, ok := name.(string); ok {
+			names = append(names, s)
+		} else {
+			l.errorf(fmt.Sprintf("%s.env[%d]", path, k), "expected a string, got %s", specTypeName(name))
+		}
+	}
+	if len(names) > 0 {
+		if err := h.BindEnv(aliases[0], names...); err != nil {
+			l.errorf(fieldPath(path, "env"), "%s", err)
+		}
+	}
+	l.validators(path, obj, h, aliases[0])
+}
+
+// Add the group of parameters described at `path` to a subcommand.
+func (l *specLoader) group(path string, v interface{}, h *SubcommandHandler) {
+	obj, ok := l.object(path, v, "kind", "labels")
+	if !ok {
+		return
+	}
+	labels := make([]string, 0)
+	for k, label := range l.list(path, obj, "labels") {
+		if s, ok := label.(string); ok {
+			labels = append(labels, s)
+		} else {
+			l.errorf(fmt.Sprintf("%s.labels[%d]", path, k), "expected a string, got %s", specTypeName(label))
+		}
+	}
+	adders := map[groupKind]func(labels ...string) error{groupExclusive: h.MutuallyExclusive,
+		groupExactlyOne: h.ExactlyOneOf, groupAtLeastOne: h.AtLeastOneOf, groupTogether: h.RequiredTogether}
+	kind := l.str(path, obj, "kind")
+	add, ok := adders[groupKind(kind)]
+	if !ok {
+		l.errorf(fieldPath(path, "kind"), "unknown kind %q, expected one of exclusive, exactly_one, at_least_one, together", kind)
+		return
+	}
+	if err := add(labels...); err != nil {
+		l.errorf(path, "%s", err)
+	}
+}
+
+// Add the positional argument described at `path` to a subcommand.
+func (l *specLoader) positional(path string, v interface{}, h *SubcommandHandler) {
+	obj, ok := l.object(path, v, "name", "type", "required", "variadic", "choices", "ignore_case", "min", "max", "default", "validators", "documentation")
+	if !ok {
+		return
+	}
+	name := l.str(path, obj, "name")
+	doc := l.str(path, obj, "documentation")
+	var err error
+	if l.boolean(path, obj, "variadic") {
+		if t := l.str(path, obj, "type"); t != "" && t != "str" {
+			l.errorf(fieldPath(path, "type"), "a variadic argument must have type str")
+			return
+		}
+		err = h.AddVariadicPositional(name, doc, l.integer(path, obj, "min", 0), l.integer(path, obj, "max", -1))
+	} else {
+		t := l.typeName(path, obj)
+		required := true
+		if _, ok := obj["required"]; ok {
+			required = l.boolean(path, obj, "required")
+		}
+		deflt, hasDefault := obj["default"]
+		if required && hasDefault {
+			l.errorf(fieldPath(path, "default"), "a required argument cannot have a default")
+			return
+		}
+		if t == "" {
+			return
+		}
+		if strings.HasPrefix(t, "[]") {
+			l.errorf(fieldPath(path, "type"), "a positional argument cannot have a list type, use a variadic argument instead")
+			return
+		}
+		if t == "choice" {
+			if !required {
+				l.errorf(fieldPath(path, "type"), "an optional positional argument cannot have type choice")
+				return
+			}
+			choices, docs := l.choices(path, obj)
+			if err := h.AddChoicePositional(name, doc, choices); err != nil {
+				l.errorf(path, "%s", err)
+				return
+			}
+			l.choiceOptions(path, obj, docs, h, name)
+		} else if value := newRichValue(t, nil); value != nil {
+			if !required {
+				l.errorf(fieldPath(path, "type"), "an optional positional argument cannot have type %s", t)
+				return
+			}
+			err = h.AddValuePositional(name, doc, value)
+		} else if required {
+			switch t {
+			case "int":
+				err = h.AddIntPositional(name, doc)
+			case "str":
+				err = h.AddStrPositional(name, doc)
+			case "float":
+				err = h.AddFloatPositional(name, doc)
+			case "bool":
+				err = fmt.Errorf("a positional argument cannot have type bool")
+			}
+		} else {
+			value := map[string]interface{}{"int": 0, "str": "", "float": 0.0, "bool": false}[t]
+			if hasDefault {
+				if value, ok = l.value(fieldPath(path, "default"), deflt, t); !ok {
+					return
+				}
+			}
+			switch t {
+			case "int":
+				err = h.AddOptionalIntPositional(name, doc, value.(int))
+			case "str":
+				err = h.AddOptionalStrPositional(name, doc, value.(string))
+			case "float":
+				err = h.AddOptionalFloatPositional(name, doc, value.(float64))
+			case "bool":
+				err = fmt.Errorf("a positional argument cannot have type bool")
+			}
+		}
+	}
+	if err != nil {
+		l.errorf(path, "%s", err)
+		return
+	}
+	l.validators(path, obj, h, name)
+}
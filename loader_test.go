@@ -0,0 +1,150 @@
+package goldcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const calculatorYAML = `
+# a declarative calculator
+version: "1.0"
+documentation: A simple calculator CLI app.
+subcommands:
+  - name: add
+    documentation: Add two numbers together.
+    labels:
+      - aliases: [first, f]
+        type: int
+        required: true
+        documentation: first integer argument
+      - name: second
+        type: int
+        default: 2
+        documentation: second integer argument
+    examples:
+      - documentation: with flags
+        command: calculator add -f 1 --second 34
+        output: "35"
+`
+
+func TestNewCliFromYAML(t *testing.T) {
+	cli, err := NewCliFromYAML([]byte(calculatorYAML), Handlers{
+		"add": func(h *SubcommandHandler) {
+			first, _ := h.GetInt("f")
+			second, _ := h.GetInt("second")
+			h.Stdout().Write([]byte(strings.Repeat("+", first+second)))
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	code, out, errOut := execute(&cli, "add", "--first", "1")
+	if code != 0 || out != "+++" {
+		t.Fatalf("unexpected result %d %q %q", code, out, errOut)
+	}
+	if err := cli.Validate(); err != nil {
+		t.Fatalf("the loaded CLI should be valid: %s", err)
+	}
+}
+
+func TestNewCliFromJSONRoundTrip(t *testing.T) {
+	tree, _ := sampleTreeCli(t)
+	tree.SetName("app")
+	var b bytes.Buffer
+	writeJSON(&b, tree.Spec())
+	noop := func(h *SubcommandHandler) {}
+	cli, err := NewCliFromJSON(b.Bytes(), Handlers{"db migrate": noop, "db migrate up": noop})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(cli.Spec(), tree.Spec()) {
+		t.Fatalf("the loaded CLI differs from the original:\n%+v\n%+v", cli.Spec(), tree.Spec())
+	}
+}
+
+func TestNewCliFromJSONErrors(t *testing.T) {
+	spec := map[string]interface{}{
+		"version": "1.0",
+		"subcommands": []interface{}{
+			map[string]interface{}{
+				"name":    "add",
+				"handler": "ad",
+				"labels": []interface{}{
+					map[string]interface{}{"name": "first", "type": "integer"},
+					map[string]interface{}{"name": "second", "type": "int", "default": "two"},
+					map[string]interface{}{"name": "third", "type": "int", "docs": "x"},
+				},
+			},
+		},
+	}
+	data, _ := json.Marshal(spec)
+	_, err := NewCliFromJSON(data, Handlers{"add": func(h *SubcommandHandler) {}})
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	want := []string{
+		`subcommands[0].labels[0].type: unknown type "integer", expected one of int, str, float, bool, []int, []str, []float, duration, time, url, ip, ip:port, cidr, size, regexp, choice`,
+		`subcommands[0].labels[1].default: expected a value of type int, got a string`,
+		`subcommands[0].labels[2].docs: unknown field`,
+		`subcommands[0]: no handler named "ad", did you mean "add"?`,
+		`the handler "add" is not used by the specification`,
+	}
+	if len(list) != len(want) {
+		t.Fatalf("unexpected errors:\n%s", err)
+	}
+	for k, e := range list {
+		if e.Error() != want[k] {
+			t.Fatalf("expected error %q, got %q", want[k], e)
+		}
+	}
+	if _, err := NewCliFromJSON([]byte("{\n\"version\": 1,\n}"), nil); err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Fatalf("expected a syntax error with a line number, got %v", err)
+	}
+}
+
+func TestNewCliFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calculator.yml")
+	os.WriteFile(path, []byte(calculatorYAML), 0644)
+	if _, err := NewCliFromFile(path, Handlers{"add": func(h *SubcommandHandler) {}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err := NewCliFromFile(path, nil)
+	if err == nil || err.Error() != path+`: subcommands[0]: no handler named "add"` {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestNewCliFromJSONReservedNames(t *testing.T) {
+	tree, _ := sampleTreeCli(t)
+	tree.SetName("app")
+	spec := tree.Spec()
+	for k := range spec.Subcommands {
+		if spec.Subcommands[k].Name == "version" {
+			spec.Subcommands[k].Documentation = "Print something else."
+		}
+	}
+	spec.Subcommands = append(spec.Subcommands, SubcommandSpec{Name: "help", Documentation: "My own help."})
+	data, _ := json.Marshal(spec)
+	_, err := NewCliFromJSON(data, Handlers{"db migrate": func(h *SubcommandHandler) {},
+		"db migrate up": func(h *SubcommandHandler) {}})
+	for _, want := range []string{
+		`subcommands[1].name: the name "version" is reserved`,
+		fmt.Sprintf(`subcommands[%d].name: the name "help" is reserved`, len(spec.Subcommands)-1),
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+	_, err = NewCliFromJSON([]byte(`{"documentation": "A CLI.", "subcommands": [{"name": "run"}]}`),
+		Handlers{"run": func(h *SubcommandHandler) {}})
+	if err == nil || !strings.Contains(err.Error(), `subcommand "run": the subcommand has no documentation`) {
+		t.Fatalf("the loaded CLI should be validated, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package goldcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Escape text for roff, so that backslashes, hyphens, and control characters
+// at the start of a line are printed as they are.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\e")
+	s = strings.ReplaceAll(s, "-", "\\-")
+	lines := strings.Split(s, "\n")
+	for k, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[k] = "\\&" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Get the roff list of the flags of a parser, one tagged paragraph per label.
+func roffFlags(cp *commandParser) string {
+	s := ""
+	for _, row := range cp.labelRows() {
+		names := make([]string, 0, len(row))
+		for _, alias := range row {
+			names = append(names, "\\fB"+roffEscape(cp.flagName(alias))+"\\fR")
+		}
+		s = s + ".TP\n" + strings.Join(names, ", ")
+		if !cp.isBool(row[0]) {
+			s = s + " \\fI" + cp.labelType(row[0]) + "\\fR"
+		}
+		s = s + "\n" + roffEscape(cp.docOf(row[0])) + "\n"
+		if names := cp.envNames(row[0]); len(names) > 0 {
+			s = s + "Environment: " + roffEscape(strings.Join(names, ", ")) + "\n"
+		}
+	}
+	return s
+}
+
+// Get the name of the man page for a command path e.g. "app-db-migrate".
+func manPageName(path []string) string {
+	return strings.Join(path, "-")
+}
+
+// Get the man page for the subcommand at `path`, which holds the program
+// name and the command words leading to the subcommand. The argument
+// `version` is the version of the CLI.
+func (h *SubcommandHandler) manPage(path []string, version string) string {
+	name := manPageName(path)
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH \"%s\" 1 \"\" \"%s\" \"User Commands\"\n", strings.ToUpper(roffEscape(name)), roffEscape(path[0]+" "+version))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roffEscape(name), roffEscape(firstLine(h.documentation)))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n", roffEscape(strings.Join(path, " ")))
+	if synopsis := strings.TrimSpace(strings.TrimPrefix(h.usage(nil), "usage:")); synopsis != "" {
+		fmt.Fprintf(&b, "%s\n", roffEscape(synopsis))
+	}
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", roffEscape(h.documentation))
+	if children := visible(h.subcommands); len(children) > 0 {
+		fmt.Fprintf(&b, ".SH SUBCOMMANDS\n")
+		for _, child := range children {
+			fmt.Fprintf(&b, ".TP\n\\fB%s\\fR\n%s\n", roffEscape(child.name), roffEscape(child.documentation))
+		}
+	}
+	if len(h.positionals) > 0 || len(h.argparser.allLabels) > 0 {
+		fmt.Fprintf(&b, ".SH ARGUMENTS\n")
+		for _, p := range h.positionals {
+			fmt.Fprintf(&b, ".TP\n\\fI%s\\fR\n%s\n", roffEscape(p.usageToken()), roffEscape(p.documentation))
+		}
+		b.WriteString(roffFlags(h.argparser))
+	}
+	fmt.Fprintf(&b, ".SH OPTIONS\n")
+	b.WriteString(roffFlags(h.paramparser))
+	fmt.Fprintf(&b, ".TP\n\\fB\\-\\-help\\fR, \\fB\\-h\\fR\nprint help\n")
+	if len(h.examples) > 0 {
+		fmt.Fprintf(&b, ".SH EXAMPLES\n")
+		for _, ex := range h.examples {
+			if ex.documentation != "" {
+				fmt.Fprintf(&b, ".PP\n%s\n", roffEscape(ex.documentation))
+			}
+			fmt.Fprintf(&b, ".PP\n.RS\n.nf\n$ %s\n", roffEscape(ex.commandLine(path[0])))
+			if ex.output != "" {
+				fmt.Fprintf(&b, "%s\n", roffEscape(ex.output))
+			}
+			fmt.Fprintf(&b, ".fi\n.RE\n")
+		}
+	}
+	see := []string{manPageName(path[:len(path)-1])}
+	for _, child := range visible(h.subcommands) {
+		see = append(see, manPageName(append(append([]string{}, path...), child.name)))
+	}
+	fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", roffSeeAlso(see))
+	return b.String()
+}
+
+// Get the SEE ALSO references to a list of man pages.
+func roffSeeAlso(pages []string) string {
+	refs := make([]string, 0, len(pages))
+	for _, page := range pages {
+		refs = append(refs, "\\fB"+roffEscape(page)+"\\fR(1)")
+	}
+	return strings.Join(refs, ", ")
+}
+
+// Get the first line of a documentation string.
+func firstLine(doc string) string {
+	if i := strings.IndexByte(doc, '\n'); i >= 0 {
+		return doc[:i]
+	}
+	return doc
+}
+
+// Get the top-level man page for the CLI, where `prog` is the program name.
+func (cli *Cli) manPage(prog string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH \"%s\" 1 \"\" \"%s\" \"User Commands\"\n", strings.ToUpper(roffEscape(prog)), roffEscape(prog+" "+cli.version))
+	fmt.Fprintf(&b, ".SH NAME\n%s \\- %s\n", roffEscape(prog), roffEscape(firstLine(cli.documentation)))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B %s\n\\fIsubcommand\\fR [\\fIarguments\\fR]\n", roffEscape(prog))
+	fmt.Fprintf(&b, ".SH DESCRIPTION\n%s\n", roffEscape(cli.documentation))
+	fmt.Fprintf(&b, ".SH SUBCOMMANDS\n")
+	see := make([]string, 0)
+	for _, subcmd := range visible(cli.allSubcommands()) {
+		fmt.Fprintf(&b, ".TP\n\\fB%s\\fR\n%s\n", roffEscape(subcmd.name), roffEscape(subcmd.documentation))
+		see = append(see, manPageName([]string{prog, subcmd.name}))
+	}
+	fmt.Fprintf(&b, ".TP\n\\fBhelp\\fR [\\fIsubcommand\\fR]\nprint help for the app or a subcommand\n")
+	fmt.Fprintf(&b, ".SH OPTIONS\n")
+	fmt.Fprintf(&b, ".TP\n\\fB\\-\\-help\\fR, \\fB\\-h\\fR\nprint help\n")
+	fmt.Fprintf(&b, ".TP\n\\fB\\-\\-version\\fR, \\fB\\-V\\fR\nprint the version of this app\n")
+	fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", roffSeeAlso(see))
+	return b.String()
+}
+
+// Get the man pages for the CLI in roff format, by file name: one top-level
+// page named after the program e.g. "app.1", and one page per subcommand
+// e.g. "app-db-migrate.1". Hidden subcommands do not get a page.
+func (cli *Cli) ManPages() map[string]string {
+	return cli.manPages(cli.progName(nil))
+}
+
+// Get the man pages for the CLI, where `prog` is the program name.
+func (cli *Cli) manPages(prog string) map[string]string {
+	cli.configureEnv()
+	pages := map[string]string{prog + ".1": cli.manPage(prog)}
+	cli.walk(prog, func(path []string, h *SubcommandHandler) {
+		pages[manPageName(path)+".1"] = h.manPage(path, cli.version)
+	})
+	return pages
+}
+
+// Write the man pages for the CLI to a directory, which is created if it does
+// not exist. The paths of the written files are returned in order.
+func (cli *Cli) WriteManPages(dir string) ([]string, error) {
+	return cli.writeManPages(dir, cli.progName(nil))
+}
+
+// Write the man pages for the CLI to a directory, where `prog` is the program
+// name.
+func (cli *Cli) writeManPages(dir string, prog string) ([]string, error) {
+	return writeFiles(dir, cli.manPages(prog))
+}
+
+// Write files to a directory, which is created if it does not exist. The
+// keys of `files` are the file names, and the values are the contents. The
+// paths of the written files are returned in order.
+func writeFiles(dir string, files map[string]string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	written := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// Get the built-in, hidden 'gen-man' subcommand, which writes the man pages
+// to a directory.
+func (cli *Cli) genManSubcommand() *SubcommandHandler {
+	h, _ := NewSubcommandHandler("gen-man", "write the man pages of this app to a directory")
+	h.AddStrPositional("dir", "the directory to write the man pages to")
+	h.SetCompletion("dir", CompleteDirectoryNames())
+	h.SetHidden(true)
+	h.Handle(func(h *SubcommandHandler) {
+		dir, _ := h.GetStr("dir")
+		written, err := cli.writeManPages(dir, h.path[0])
+		for _, path := range written {
+			fmt.Fprintf(h.Stdout(), "%s\n", path)
+		}
+		if err != nil {
+			fmt.Fprintf(h.Stderr(), "error: %s\n", err)
+			h.SetExitCode(1)
+		}
+	})
+	return h
+}
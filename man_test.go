@@ -0,0 +1,62 @@
+package goldcmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManPages(t *testing.T) {
+	cli, _ := sampleTreeCli(t)
+	cli.SetName("app")
+	pages := cli.ManPages()
+	for _, name := range []string{"app.1", "app-db.1", "app-db-migrate.1", "app-db-migrate-up.1", "app-version.1", "app-completion.1"} {
+		if _, ok := pages[name]; !ok {
+			t.Fatalf("missing man page %s", name)
+		}
+	}
+	if _, ok := pages["app-gen-man.1"]; ok {
+		t.Fatalf("hidden subcommands should not get a man page")
+	}
+	page := pages["app-db-migrate-up.1"]
+	for _, want := range []string{
+		".TH \"APP\\-DB\\-MIGRATE\\-UP\" 1",
+		".SH NAME\napp\\-db\\-migrate\\-up \\- Apply migrations.",
+		".SH SYNOPSIS\n.B app db migrate up\n[OPTIONS]",
+		".SH OPTIONS\n.TP\n\\fB\\-\\-steps\\fR \\fIint\\fR\nhow many migrations to apply",
+		".SH SEE ALSO\n\\fBapp\\-db\\-migrate\\fR(1)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("expected %q in man page:\n%s", want, page)
+		}
+	}
+	if !strings.Contains(pages["app.1"], "\\fBapp\\-db\\fR(1)") {
+		t.Fatalf("the top-level page should refer to its subcommands:\n%s", pages["app.1"])
+	}
+}
+
+func TestManPageEscaping(t *testing.T) {
+	if got := roffEscape(".start with a dot\nback\\slash"); got != "\\&.start with a dot\nback\\eslash" {
+		t.Fatalf("unexpected escaped text %q", got)
+	}
+}
+
+func TestGenMan(t *testing.T) {
+	cli, _ := sampleTreeCli(t)
+	dir := filepath.Join(t.TempDir(), "man")
+	code, out, errOut := execute(&cli, "gen-man", dir)
+	if code != 0 {
+		t.Fatalf("unexpected failure %q", errOut)
+	}
+	if !strings.Contains(out, filepath.Join(dir, "app-db-migrate.1")) {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.1")); err != nil {
+		t.Fatalf("expected the top-level man page to be written: %s", err)
+	}
+	_, out, _ = execute(&cli, "help")
+	if strings.Contains(out, "gen-man") {
+		t.Fatalf("gen-man should be hidden from help, got %q", out)
+	}
+}
@@ -0,0 +1,222 @@
+package goldcmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A positional argument of a subcommand, i.e. a value given on the command
+// line without a label, like the 'SRC' and 'DST' in 'cp SRC DST'.
+type positional struct {
+	// the name used to refer to the value e.g. in GetStr
+	name string
+	// documentation for the argument
+	documentation string
+	// the type name of the value e.g. "int"
+	typeName string
+	// if set, the argument may be left out and a default is used
+	optional bool
+	// if set, the argument takes every remaining value
+	variadic bool
+	// the minimum and maximum number of values of a variadic argument,
+	// where a negative maximum means there is no limit
+	min int
+	max int
+}
+
+// Get the token for the positional argument in a usage line e.g. "<src>",
+// "[mode]", or "[files...]".
+func (p *positional) usageToken() string {
+	if p.variadic {
+		if p.min > 0 {
+			return "<" + p.name + ">..."
+		}
+		return "[" + p.name + "...]"
+	}
+	if p.optional {
+		return "[" + p.name + "]"
+	}
+	return "<" + p.name + ">"
+}
+
+// Get the help line for the positional argument, where `rules` describe its
+// validators.
+func (p *positional) helpString(rules []string) string {
+	notes := make([]string, 0)
+	if p.typeName != "str" {
+		notes = append(notes, p.typeName)
+	}
+	notes = append(notes, rules...)
+	if p.variadic {
+		if p.max >= 0 {
+			notes = append(notes, fmt.Sprintf("%d to %d values", p.min, p.max))
+		} else if p.min > 0 {
+			notes = append(notes, fmt.Sprintf("at least %d values", p.min))
+		}
+	}
+	s := " " + p.usageToken() + "\t" + p.documentation
+	if len(notes) > 0 {
+		s = s + " (" + strings.Join(notes, ", ") + ")"
+	}
+	return s + "\n"
+}
+
+// Check that a positional argument can be added after the existing ones.
+func (h *SubcommandHandler) checkPositionalAllowed(name string, optional bool) error {
+	if !h.checkAliasesAllowed([]string{name}) {
+		return errors.New("invalid label value")
+	}
+	if len(h.positionals) > 0 {
+		last := h.positionals[len(h.positionals)-1]
+		if last.variadic {
+			return errors.New("no positional argument can follow a variadic one")
+		}
+		if last.optional && !optional {
+			return errors.New("a required positional argument cannot follow an optional one")
+		}
+	}
+	return nil
+}
+
+// Add a positional argument with a label in the positional parser, which
+// holds its value. The current value of an optional argument is its default.
+func (h *SubcommandHandler) addPositional(p *positional, value Value) error {
+	if err := h.checkPositionalAllowed(p.name, p.optional); err != nil {
+		return err
+	}
+	p.typeName = value.Type()
+	if p.optional {
+		h.posparser.addLabelWithDefault([]string{p.name}, p.documentation, value)
+	} else {
+		h.posparser.addLabel([]string{p.name}, p.documentation, value)
+	}
+	h.positionals = append(h.positionals, p)
+	return nil
+}
+
+// Add a required integer positional argument to the subcommand.
+// Positional arguments are filled in the order they are added, and their
+// values are available through GetInt. The name must not be used by another
+// argument or parameter.
+func (h *SubcommandHandler) AddIntPositional(name string, doc string) error {
+	return h.addPositional(&positional{name: name, documentation: doc}, new(intValue))
+}
+
+// Add a required float positional argument to the subcommand.
+// The value is available through GetFloat.
+func (h *SubcommandHandler) AddFloatPositional(name string, doc string) error {
+	return h.addPositional(&positional{name: name, documentation: doc}, new(floatValue))
+}
+
+// Add a required string positional argument to the subcommand.
+// The value is available through GetStr.
+func (h *SubcommandHandler) AddStrPositional(name string, doc string) error {
+	return h.addPositional(&positional{name: name, documentation: doc}, new(strValue))
+}
+
+// Add an optional integer positional argument to the subcommand with a
+// default value. Optional positional arguments must come after the required
+// ones.
+func (h *SubcommandHandler) AddOptionalIntPositional(name string, doc string, deflt int) error {
+	value := intValue(deflt)
+	return h.addPositional(&positional{name: name, documentation: doc, optional: true}, &value)
+}
+
+// Add an optional float positional argument to the subcommand with a default
+// value.
+func (h *SubcommandHandler) AddOptionalFloatPositional(name string, doc string, deflt float64) error {
+	value := floatValue(deflt)
+	return h.addPositional(&positional{name: name, documentation: doc, optional: true}, &value)
+}
+
+// Add an optional string positional argument to the subcommand with a
+// default value.
+func (h *SubcommandHandler) AddOptionalStrPositional(name string, doc string, deflt string) error {
+	value := strValue(deflt)
+	return h.addPositional(&positional{name: name, documentation: doc, optional: true}, &value)
+}
+
+// Add a variadic positional argument that takes every remaining value, as a
+// list of strings available through GetStrs.
+// At least `min` values must be given, and at most `max` if it is not
+// negative. The variadic argument must be the last positional argument.
+func (h *SubcommandHandler) AddVariadicPositional(name string, doc string, min int, max int) error {
+	if min < 0 || (max >= 0 && max < min) {
+		return errors.New("invalid value count")
+	}
+	if err := h.checkPositionalAllowed(name, min == 0); err != nil {
+		return err
+	}
+	h.positionals = append(h.positionals, &positional{name: name, documentation: doc,
+		typeName: "str", optional: min == 0, variadic: true, min: min, max: max})
+	return nil
+}
+
+// Get the values of a string list argument or parameter, or of a variadic
+// positional argument.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetStrs(key string) ([]string, error) {
+	if val, b := h.get(key).([]string); b {
+		return val, nil
+	}
+	if val, b := h.variadicValues[key]; b {
+		return val, nil
+	}
+	return nil, errors.New("key not available")
+}
+
+// Assign the values at the indexes `operands` of `args` to the positional
+// arguments, in order. Values left over from index `end` onwards, after the
+// end of the options, are kept as the remaining arguments, and so are other
+// left over values if `allowUnknown` is set, since they may be the values of
+// unknown flags e.g. 'bar' in '--foo bar'. Otherwise they are errors.
+func (h *SubcommandHandler) assignPositionals(args []string, operands []int, end int, allowUnknown bool) ErrorList {
+	errs := make(ErrorList, 0)
+	h.variadicValues = make(map[string][]string)
+	h.remainingArgs = make([]string, 0)
+	k := 0
+	for _, p := range h.positionals {
+		if p.variadic {
+			last := len(operands)
+			if p.max >= 0 && k+p.max < last {
+				last = k + p.max
+			}
+			if last-k < p.min {
+				errs = append(errs, fmt.Errorf("expected at least %d values for %s", p.min, p.usageToken()))
+			}
+			values := make([]string, 0, last-k)
+			if k < last {
+				h.posparser.sources[p.name] = Source{Kind: SourceCommandLine, Index: operands[k]}
+			}
+			for ; k < last; k++ {
+				values = append(values, args[operands[k]])
+			}
+			h.variadicValues[p.name] = values
+			continue
+		}
+		if k >= len(operands) {
+			if !p.optional {
+				errs = append(errs, fmt.Errorf("missing required argument %s", p.usageToken()))
+			}
+			continue
+		}
+		if err := h.posparser.tryToUseFlag(p.name, args[operands[k]]); err != nil {
+			errs = append(errs, &ParseError{Args: args, Token: args[operands[k]], Index: operands[k],
+				Label: p.name, Type: p.typeName, Err: err, Positional: true})
+		} else {
+			h.posparser.sources[p.name] = Source{Kind: SourceCommandLine, Index: operands[k]}
+		}
+		k++
+	}
+	for ; k < len(operands); k++ {
+		if operands[k] >= end || allowUnknown {
+			h.remainingArgs = append(h.remainingArgs, args[operands[k]])
+			continue
+		}
+		errs = append(errs, &ParseError{Args: args, Token: args[operands[k]], Index: operands[k],
+			Err: ErrUnexpectedArgument})
+	}
+	return errs
+}
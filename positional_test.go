@@ -0,0 +1,165 @@
+package goldcmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func samplePositionalHandler(t *testing.T) *SubcommandHandler {
+	h, err := NewSubcommandHandler("cp", "Copy files.")
+	if err != nil {
+		t.Fatalf("could not create subcommand: %v", err)
+	}
+	if err := h.AddBoolParamWithDefault([]string{"force", "f"}, "overwrite files", false); err != nil {
+		t.Fatalf("could not add parameter: %v", err)
+	}
+	if err := h.AddStrPositional("dst", "where to copy to"); err != nil {
+		t.Fatalf("could not add positional: %v", err)
+	}
+	if err := h.AddOptionalIntPositional("mode", "file mode", 644); err != nil {
+		t.Fatalf("could not add positional: %v", err)
+	}
+	if err := h.AddVariadicPositional("src", "files to copy", 0, 2); err != nil {
+		t.Fatalf("could not add positional: %v", err)
+	}
+	return h
+}
+
+func TestPositionalOrdering(t *testing.T) {
+	h := samplePositionalHandler(t)
+	if err := h.AddStrPositional("more", "another"); err == nil {
+		t.Fatalf("no positional should follow a variadic one")
+	}
+	h, _ = NewSubcommandHandler("x", "")
+	h.AddOptionalStrPositional("a", "", "")
+	if err := h.AddStrPositional("b", ""); err == nil {
+		t.Fatalf("a required positional should not follow an optional one")
+	}
+	if err := h.AddStrArg([]string{"a"}, ""); err == nil {
+		t.Fatalf("a flag should not reuse a positional name")
+	}
+}
+
+func TestParsePositionals(t *testing.T) {
+	h := samplePositionalHandler(t)
+	if err := h.parseFlags([]string{"app", "cp", "out", "-f", "-755", "a", "b"}, 2, false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dst, _ := h.GetStr("dst")
+	mode, _ := h.GetInt("mode")
+	src, _ := h.GetStrs("src")
+	force, _ := h.GetBool("force")
+	if dst != "out" || mode != -755 || len(src) != 2 || src[1] != "b" || !force {
+		t.Fatalf("unexpected values dst=%q mode=%d src=%v force=%t", dst, mode, src, force)
+	}
+
+	if err := h.parseFlags([]string{"app", "cp", "out"}, 2, false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	mode, _ = h.GetInt("mode")
+	src, err := h.GetStrs("src")
+	if mode != 644 || err != nil || len(src) != 0 {
+		t.Fatalf("optional positionals should take their defaults")
+	}
+}
+
+func TestParsePositionalErrors(t *testing.T) {
+	h := samplePositionalHandler(t)
+	err := h.parseFlags([]string{"app", "cp"}, 2, false)
+	if err == nil || !strings.Contains(err.Error(), "missing required argument <dst>") {
+		t.Fatalf("expected a missing argument error, got %v", err)
+	}
+	err = h.parseFlags([]string{"app", "cp", "out", "rw", "a", "b", "c"}, 2, false)
+	list, _ := err.(ErrorList)
+	if len(list) != 2 {
+		t.Fatalf("expected two errors, got %v", err)
+	}
+	if !strings.Contains(list[0].Error(), "invalid value \"rw\" for <mode>") {
+		t.Fatalf("unexpected error %v", list[0])
+	}
+	if pe, ok := list[1].(*ParseError); !ok || pe.Err != ErrUnexpectedArgument || pe.Token != "c" {
+		t.Fatalf("unexpected error %v", list[1])
+	}
+}
+
+func TestPositionalHelp(t *testing.T) {
+	h := samplePositionalHandler(t)
+	if u := h.usage([]string{"app", "cp"}); u != "usage: app cp [OPTIONS] <dst> [mode] [src...]" {
+		t.Fatalf("unexpected usage %q", u)
+	}
+	var b bytes.Buffer
+	h.printArgumentHelp(&b)
+	for _, want := range []string{" <dst>\twhere to copy to\n", " [mode]\tfile mode (int)\n", " [src...]\tfiles to copy (0 to 2 values)\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("help should contain %q, got %q", want, b.String())
+		}
+	}
+}
+
+func TestTerminator(t *testing.T) {
+	h, _ := NewSubcommandHandler("run", "Run a command.")
+	h.AddBoolParamWithDefault([]string{"v"}, "verbose", false)
+	if err := h.parseFlags([]string{"tool", "run", "-v", "--", "go", "test", "-v", "./..."}, 2, false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	rest := h.RemainingArgs()
+	if strings.Join(rest, " ") != "go test -v ./..." {
+		t.Fatalf("unexpected remaining args %v", rest)
+	}
+
+	h = samplePositionalHandler(t)
+	if err := h.parseFlags([]string{"app", "cp", "--force", "--", "-out", "1", "-a", "-b", "-c"}, 2, false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dst, _ := h.GetStr("dst")
+	src, _ := h.GetStrs("src")
+	if dst != "-out" || strings.Join(src, " ") != "-a -b" || strings.Join(h.RemainingArgs(), " ") != "-c" {
+		t.Fatalf("operands after \"--\" should fill positionals, got dst=%q src=%v rest=%v", dst, src, h.RemainingArgs())
+	}
+}
+
+func TestStopAtFirstNonOption(t *testing.T) {
+	h, _ := NewSubcommandHandler("exec", "Run a program.")
+	h.AddBoolParamWithDefault([]string{"v"}, "verbose", false)
+	h.AddStrPositional("program", "the program to run")
+	h.StopAtFirstNonOption(true)
+	if err := h.parseFlags([]string{"app", "exec", "-v", "ls", "-la", "-v"}, 2, false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	program, _ := h.GetStr("program")
+	if program != "ls" || strings.Join(h.RemainingArgs(), " ") != "-la -v" {
+		t.Fatalf("unexpected program %q and remaining args %v", program, h.RemainingArgs())
+	}
+	if v, _ := h.GetBool("v"); !v {
+		t.Fatalf("flags before the first positional should be parsed")
+	}
+}
+
+func TestBadFlagValueIsNotAnOperand(t *testing.T) {
+	h := samplePositionalHandler(t)
+	h.AddIntParamWithDefault([]string{"count"}, "how many copies", 1)
+	for _, args := range [][]string{
+		{"app", "cp", "--count", "abc", "out", "755", "a", "b"},
+		{"app", "cp", "out", "--count=abc", "755", "a", "b"},
+	} {
+		err := h.parseFlags(args, 2, false)
+		list, _ := err.(ErrorList)
+		if len(list) != 1 || !strings.Contains(list[0].Error(), "invalid value \"abc\" for --count") {
+			t.Fatalf("%v: expected one error, got %v", args, err)
+		}
+		dst, _ := h.GetStr("dst")
+		mode, _ := h.GetInt("mode")
+		src, _ := h.GetStrs("src")
+		if dst != "out" || mode != 755 || len(src) != 2 || src[0] != "a" || src[1] != "b" {
+			t.Fatalf("%v: unexpected positionals dst=%q mode=%d src=%v", args, dst, mode, src)
+		}
+	}
+	err := h.parseFlags([]string{"app", "cp", "out", "--count", "-f"}, 2, false)
+	if list, _ := err.(ErrorList); len(list) != 1 {
+		t.Fatalf("a flag after a bad value should still be parsed, got %v", err)
+	}
+	if force, _ := h.GetBool("force"); !force {
+		t.Fatalf("expected --force to be set")
+	}
+}
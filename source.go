@@ -0,0 +1,118 @@
+package goldcmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// The kind of place the value of a label comes from.
+type SourceKind int
+
+const (
+	// the label has no value
+	SourceUnset SourceKind = iota
+	// the value is the default of the label
+	SourceDefault
+	// the value was given on the command line
+	SourceCommandLine
+	// the value was read from an environment variable
+	SourceEnv
+	// the value was read from a configuration file
+	SourceConfig
+)
+
+// Where the value of a label comes from.
+type Source struct {
+	Kind SourceKind
+	// the index of the token that set the value, in the command line given
+	// to Cli.Execute, for a value from the command line
+	Index int
+	// the name of the variable, for a value from the environment
+	Env string
+	// the file and line, for a value from a configuration file
+	File string
+	Line int
+}
+
+// Get a description of the source e.g. "command line (token 3)".
+func (s Source) String() string {
+	switch s.Kind {
+	case SourceDefault:
+		return "default"
+	case SourceCommandLine:
+		return fmt.Sprintf("command line (token %d)", s.Index)
+	case SourceEnv:
+		return fmt.Sprintf("environment ($%s)", s.Env)
+	case SourceConfig:
+		return fmt.Sprintf("config file (%s:%d)", s.File, s.Line)
+	}
+	return "unset"
+}
+
+// Get where the value of a label or positional argument comes from.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) Source(label string) (Source, error) {
+	var source Source
+	if p := h.positional(label); p != nil {
+		source = h.posparser.sources[p.name]
+	} else if cp := parserForAlias(label, []*commandParser{h.argparser, h.paramparser}); cp != nil {
+		source = cp.sources[cp.aliasesOf(label)[0]]
+	} else {
+		return Source{}, fmt.Errorf("unknown label \"%s\"", label)
+	}
+	if source.Kind == SourceCommandLine && source.Index < len(h.tokenIndex) {
+		source.Index = h.tokenIndex[source.Index]
+	}
+	return source, nil
+}
+
+// Return true if the value of a label or positional argument was given by
+// the user, on the command line, in the environment, or in a configuration
+// file, rather than being its default.
+func (h *SubcommandHandler) IsSet(label string) bool {
+	source, err := h.Source(label)
+	if err != nil {
+		return false
+	}
+	return source.Kind == SourceCommandLine || source.Kind == SourceEnv || source.Kind == SourceConfig
+}
+
+// Get the value of the label an alias belongs to, formatted as it would be
+// given on the command line. The second return value is false if the label
+// has no value.
+func (cp *commandParser) valueOf(alias string) (string, bool) {
+	l := cp.labelOf(alias)
+	if l == nil || !cp.isSet(alias) {
+		return "", false
+	}
+	if list, ok := l.value.(listValue); ok {
+		return cp.joinValues(alias, list.items()), true
+	}
+	return l.value.String(), true
+}
+
+// Print the value of every label and positional argument, with where it
+// comes from, as a table.
+func (h *SubcommandHandler) printSources(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tVALUE\tSOURCE\n")
+	for _, p := range h.positionals {
+		value, _ := h.posparser.valueOf(p.name)
+		if p.variadic {
+			value = strings.Join(h.variadicValues[p.name], " ")
+		}
+		source, _ := h.Source(p.name)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.usageToken(), value, source)
+	}
+	for _, cp := range []*commandParser{h.argparser, h.paramparser} {
+		for _, row := range cp.labelRows() {
+			value, _ := cp.valueOf(row[0])
+			source, _ := h.Source(row[0])
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", cp.flagName(row[0]), value, source)
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,127 @@
+package goldcmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	env := map[string]string{"PRECISION": "3"}
+	cli := sampleConfigCli(t, env)
+	cli.SetConfigFiles(ConfigFiles{})
+	sub, _ := NewSubcommandHandler("echo", "Echo a word.")
+	sub.AddStrPositional("word", "the word to echo")
+	sub.AddStrParamWithDefault([]string{"suffix"}, "added after the word", "")
+	sources := make(map[string]Source)
+	set := make(map[string]bool)
+	record := func(h *SubcommandHandler) {
+		for _, label := range []string{"first", "second", "precision", "word", "suffix"} {
+			if source, err := h.Source(label); err == nil {
+				sources[label] = source
+				set[label] = h.IsSet(label)
+			}
+		}
+	}
+	sub.Handle(record)
+	cli.HandleSubcommand(sub)
+	cli.subcommands[0].Handle(record)
+	path := filepath.Join(dir, "calc.toml")
+	writeConfig(t, path, "[divide]\n\nsecond = 4\n")
+	if code, _, errOut := execute(&cli, "--config", path, "divide", "--first", "10"); code != 0 {
+		t.Fatalf("unexpected error %q", errOut)
+	}
+	if s := sources["first"]; s.Kind != SourceCommandLine || s.Index != 4 || s.String() != "command line (token 4)" {
+		t.Fatalf("the index should count the global flags, got %+v", s)
+	}
+	if s := sources["second"]; s.Kind != SourceConfig || s.File != path || s.Line != 3 || !set["second"] {
+		t.Fatalf("unexpected source %+v", s)
+	}
+	if s := sources["precision"]; s.String() != "environment ($PRECISION)" || !set["precision"] {
+		t.Fatalf("unexpected source %+v", s)
+	}
+	delete(env, "PRECISION")
+	execute(&cli, "divide", "--first", "10")
+	if s := sources["precision"]; s.Kind != SourceDefault || set["precision"] {
+		t.Fatalf("unexpected source %+v", s)
+	}
+	execute(&cli, "echo", "hi")
+	if s := sources["word"]; s.Kind != SourceCommandLine || s.Index != 2 || !set["word"] {
+		t.Fatalf("unexpected source for a positional argument %+v", s)
+	}
+	if s := sources["suffix"]; s.Kind != SourceDefault || set["suffix"] {
+		t.Fatalf("unexpected source %+v", s)
+	}
+	if _, err := sub.Source("nope"); err == nil || sub.IsSet("nope") {
+		t.Fatalf("expected an error for an unknown label")
+	}
+}
+
+func TestShowConfig(t *testing.T) {
+	ran := false
+	cli := sampleConfigCli(t, map[string]string{"PRECISION": "1"})
+	cli.subcommands[0].Handle(func(h *SubcommandHandler) { ran = true })
+	for _, args := range [][]string{{"--show-config", "divide", "--first", "10"}, {"divide", "--first", "10", "--show-config"}} {
+		code, out, errOut := execute(&cli, args...)
+		if code != 0 || ran {
+			t.Fatalf("the handler should not run, got %d %q", code, errOut)
+		}
+		want := []string{
+			"NAME         VALUE  SOURCE",
+			"--first      10     command line (token ",
+			"--second     1      default",
+			"--precision  1      environment ($PRECISION)",
+		}
+		for _, line := range want {
+			if !strings.Contains(out, line) {
+				t.Fatalf("expected %q in the output, got %q", line, out)
+			}
+		}
+	}
+	if _, out, _ := execute(&cli, "help"); !strings.Contains(out, "--show-config") {
+		t.Fatalf("the help should list the global flag, got %q", out)
+	}
+	sub, _ := NewSubcommandHandler("show", "Show something.")
+	sub.AddBoolParamWithDefault([]string{"show-config"}, "show the configuration", false)
+	cli.HandleSubcommand(sub)
+	if err := cli.Validate(); err == nil || !strings.Contains(err.Error(), "the alias \"show-config\" is used by the global --show-config flag") {
+		t.Fatalf("expected a collision error, got %v", err)
+	}
+}
+
+func TestShowConfigOnlyInOptions(t *testing.T) {
+	cli := NewCli("1.0", "A CLI that runs commands.")
+	run, _ := NewSubcommandHandler("run", "Run a command.")
+	run.AddBoolParamWithDefault([]string{"v"}, "print the command first", false)
+	run.AddStrPositional("command", "the command to run")
+	run.AddVariadicPositional("args", "the arguments of the command", 0, -1)
+	run.StopAtFirstNonOption(true)
+	run.Handle(func(h *SubcommandHandler) {
+		command, _ := h.GetStr("command")
+		args, _ := h.GetStrs("args")
+		fmt.Fprintf(h.Stdout(), "%s %s", command, strings.Join(args, " "))
+	})
+	cli.HandleSubcommand(run)
+	if code, out, errOut := execute(&cli, "run", "-v", "ls", "-la", "--show-config"); code != 0 || out != "ls -la --show-config" {
+		t.Fatalf("expected the command to run, got %d %q %q", code, out, errOut)
+	}
+	if code, out, _ := execute(&cli, "run", "-v", "--show-config", "ls"); code != 0 || !strings.Contains(out, "NAME") {
+		t.Fatalf("expected the sources, got %d %q", code, out)
+	}
+}
+
+func TestParseAfterExecuteSource(t *testing.T) {
+	cli := sampleConfigCli(t, map[string]string{})
+	if code, _, errOut := execute(&cli, "--show-config", "divide", "--first", "10"); code != 0 {
+		t.Fatalf("unexpected error %q", errOut)
+	}
+	subcmd, err := cli.Parse([]string{"app", "divide", "--first", "10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s, _ := subcmd.Source("first"); s.Kind != SourceCommandLine || s.Index != 2 {
+		t.Fatalf("expected token 2, got %+v", s)
+	}
+}
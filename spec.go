@@ -0,0 +1,276 @@
+package goldcmd
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// The version of the schema of Spec. It is increased whenever a field is
+// removed or changes meaning, so tools can check they understand a spec.
+// Adding a field does not change the version.
+const SpecVersion = 1
+
+// A machine-readable description of a Cli, for tools like linters, completion
+// engines and IDE plugins. It is encoded as JSON with the field names given
+// in the struct tags.
+type Spec struct {
+	// the version of the schema, i.e. SpecVersion
+	SpecVersion int `json:"spec_version"`
+	// the name of the program
+	Name string `json:"name"`
+	// the version of the CLI
+	Version string `json:"version"`
+	// documentation for the CLI
+	Documentation string `json:"documentation"`
+	// the top-level subcommands, including the built-in ones
+	Subcommands []SubcommandSpec `json:"subcommands"`
+}
+
+// A machine-readable description of a subcommand.
+type SubcommandSpec struct {
+	// the name of the subcommand
+	Name string `json:"name"`
+	// the program name and the command words leading to the subcommand
+	Path []string `json:"path"`
+	// documentation for the subcommand
+	Documentation string `json:"documentation"`
+	// the usage line of the subcommand, as printed by help
+	Usage string `json:"usage"`
+	// true if the subcommand has a handler function, i.e. it can be run
+	// without one of its children
+	Runnable bool `json:"runnable"`
+	// the flags of the subcommand, required arguments then options
+	Labels []LabelSpec `json:"labels"`
+	// the positional arguments of the subcommand, in order
+	Positionals []PositionalSpec `json:"positionals"`
+	// the groups of parameters and the rules they follow
+	Groups []GroupSpec `json:"groups,omitempty"`
+	// true if a token with one dash is a cluster of single character
+	// flags, see SubcommandHandler.ClusterShortFlags
+	ClusterShortFlags bool `json:"cluster_short_flags,omitempty"`
+	// the examples of the subcommand
+	Examples []ExampleSpec `json:"examples"`
+	// the child subcommands
+	Subcommands []SubcommandSpec `json:"subcommands"`
+}
+
+// A machine-readable description of a flag.
+type LabelSpec struct {
+	// the first alias of the flag, which names it
+	Name string `json:"name"`
+	// every alias of the flag, including the name
+	Aliases []string `json:"aliases"`
+	// the type name of the value e.g. "int", or of the values of a list
+	// flag e.g. "[]int"
+	Type string `json:"type"`
+	// true if the flag must be given on the command line
+	Required bool `json:"required"`
+	// the values a flag of type "choice" can take
+	Choices []ChoiceSpec `json:"choices,omitempty"`
+	// true if the choices are matched regardless of case
+	IgnoreCase bool `json:"ignore_case,omitempty"`
+	// the layouts a time flag is parsed with, see time.Parse
+	Layouts []string `json:"layouts,omitempty"`
+	// the separator the values of a list flag are split on, if it has one
+	Separator string `json:"separator,omitempty"`
+	// the minimum and maximum number of values of a list flag, where a
+	// negative maximum means there is no limit
+	Min int `json:"min,omitempty"`
+	Max int `json:"max,omitempty"`
+	// the default value of an optional flag, as a JSON value of its type
+	Default interface{} `json:"default,omitempty"`
+	// the environment variables that set the flag, in the order they are
+	// looked up
+	Env []string `json:"env,omitempty"`
+	// the rules the value of the flag must follow
+	Validators []ValidatorSpec `json:"validators,omitempty"`
+	// documentation for the flag
+	Documentation string `json:"documentation"`
+}
+
+// A machine-readable description of a positional argument.
+type PositionalSpec struct {
+	// the name of the argument
+	Name string `json:"name"`
+	// the type name of the value e.g. "int"
+	Type string `json:"type"`
+	// true if a value must be given on the command line
+	Required bool `json:"required"`
+	// true if the argument takes every remaining value
+	Variadic bool `json:"variadic"`
+	// the values an argument of type "choice" can take
+	Choices []ChoiceSpec `json:"choices,omitempty"`
+	// true if the choices are matched regardless of case
+	IgnoreCase bool `json:"ignore_case,omitempty"`
+	// the minimum and maximum number of values of a variadic argument, where
+	// a negative maximum means there is no limit
+	Min int `json:"min,omitempty"`
+	Max int `json:"max,omitempty"`
+	// the default value of an optional argument, as a JSON value of its type
+	Default interface{} `json:"default,omitempty"`
+	// the rules the value of the argument must follow
+	Validators []ValidatorSpec `json:"validators,omitempty"`
+	// documentation for the argument
+	Documentation string `json:"documentation"`
+}
+
+// A machine-readable description of a group of parameters.
+type GroupSpec struct {
+	// the rule the parameters follow: "exclusive" for at most one of them,
+	// "exactly_one", "at_least_one", or "together" for all or none of them
+	Kind string `json:"kind"`
+	// the names of the parameters
+	Labels []string `json:"labels"`
+}
+
+// A machine-readable description of a rule the value of a flag or positional
+// argument must follow, see Validator.
+type ValidatorSpec struct {
+	// the rule: "at_least", "at_most", "greater_than" or "less_than" for a
+	// number, "min_length", "max_length" or "matches" for a string, or
+	// "check" for a rule made with Check, which cannot be loaded since it is
+	// a function
+	Rule string `json:"rule"`
+	// the bound of a numeric rule, where a byte size is in bytes and a
+	// duration in nanoseconds, or the length of a length rule
+	Value float64 `json:"value,omitempty"`
+	// the regular expression of a "matches" rule
+	Pattern string `json:"pattern,omitempty"`
+	// the rule as shown in help e.g. ">= 1"
+	Description string `json:"description"`
+}
+
+// A machine-readable description of one of the values a flag or positional
+// argument can take.
+type ChoiceSpec struct {
+	// the value
+	Value string `json:"value"`
+	// documentation for the value
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// A machine-readable description of an example.
+type ExampleSpec struct {
+	// documentation for the example
+	Documentation string `json:"documentation"`
+	// the text of the command
+	Command string `json:"command"`
+	// the expected output of the command
+	Output string `json:"output"`
+}
+
+// Get the default value of the label an alias belongs to. The second return
+// value is false if the label has no default.
+func (cp *commandParser) defaultValueOf(alias string) (interface{}, bool) {
+	l := cp.labelOf(alias)
+	if l == nil || !l.hasDefault {
+		return nil, false
+	}
+	return l.defaultValue, true
+}
+
+// Get the specs of the labels of a parser, in the order they were added.
+func labelSpecs(cp *commandParser, required bool) []LabelSpec {
+	specs := make([]LabelSpec, 0)
+	for _, row := range cp.labelRows() {
+		spec := LabelSpec{
+			Name:          row[0],
+			Aliases:       append([]string{}, row...),
+			Type:          cp.labelType(row[0]),
+			Required:      required,
+			Env:           cp.envNames(row[0]),
+			Documentation: cp.docOf(row[0]),
+		}
+		spec.Default, _ = cp.defaultValueOf(row[0])
+		if count, ok := cp.counts[row[0]]; ok {
+			spec.Min, spec.Max = count.min, count.max
+		}
+		spec.Separator = cp.separators[row[0]]
+		spec.Validators = cp.validatorSpecs(row[0])
+		if t, ok := cp.labelOf(row[0]).value.(*timeValue); ok {
+			spec.Layouts = t.layouts
+		}
+		if c, ok := cp.labelOf(row[0]).value.(*choiceValue); ok {
+			spec.Type, spec.Choices, spec.IgnoreCase = "choice", c.specs(), c.ignoreCase
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// Get the spec of the subcommand at `path`, which holds the program name and
+// the command words leading to the subcommand.
+func (h *SubcommandHandler) spec(path []string) SubcommandSpec {
+	spec := SubcommandSpec{
+		Name:              h.name,
+		Path:              path,
+		Documentation:     h.documentation,
+		Usage:             h.usage(path),
+		Runnable:          h.handle != nil,
+		ClusterShortFlags: h.clusterShortFlags,
+		Labels:            append(labelSpecs(h.argparser, true), labelSpecs(h.paramparser, false)...),
+		Positionals:       make([]PositionalSpec, 0, len(h.positionals)),
+		Examples:          make([]ExampleSpec, 0, len(h.examples)),
+		Subcommands:       make([]SubcommandSpec, 0),
+	}
+	for _, p := range h.positionals {
+		ps := PositionalSpec{
+			Name:          p.name,
+			Type:          p.typeName,
+			Required:      !p.optional && (!p.variadic || p.min > 0),
+			Variadic:      p.variadic,
+			Documentation: p.documentation,
+		}
+		if p.variadic {
+			ps.Min, ps.Max = p.min, p.max
+		}
+		if p.optional {
+			ps.Default, _ = h.posparser.defaultValueOf(p.name)
+		}
+		if c := h.choiceOf(p.name); c != nil {
+			ps.Type, ps.Choices, ps.IgnoreCase = "choice", c.specs(), c.ignoreCase
+		}
+		ps.Validators = h.posparser.validatorSpecs(p.name)
+		spec.Positionals = append(spec.Positionals, ps)
+	}
+	for _, g := range h.groups {
+		spec.Groups = append(spec.Groups, GroupSpec{Kind: string(g.kind), Labels: append([]string{}, g.labels...)})
+	}
+	for _, ex := range h.examples {
+		spec.Examples = append(spec.Examples, ExampleSpec{Documentation: ex.documentation, Command: ex.commandLine(path[0]), Output: ex.output})
+	}
+	for _, child := range visible(h.subcommands) {
+		spec.Subcommands = append(spec.Subcommands, child.spec(append(append([]string{}, path...), child.name)))
+	}
+	return spec
+}
+
+// Get a machine-readable description of the CLI. Hidden subcommands are left
+// out.
+func (cli *Cli) Spec() Spec {
+	return cli.spec(cli.progName(nil))
+}
+
+// Get the spec of the CLI, where `prog` is the program name.
+func (cli *Cli) spec(prog string) Spec {
+	cli.configureEnv()
+	spec := Spec{
+		SpecVersion:   SpecVersion,
+		Name:          prog,
+		Version:       cli.version,
+		Documentation: cli.documentation,
+		Subcommands:   make([]SubcommandSpec, 0),
+	}
+	for _, subcmd := range visible(cli.allSubcommands()) {
+		spec.Subcommands = append(spec.Subcommands, subcmd.spec([]string{prog, subcmd.name}))
+	}
+	return spec
+}
+
+// Write a value as indented JSON to `w`.
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
@@ -0,0 +1,66 @@
+package goldcmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpec(t *testing.T) {
+	cli := sampleCli(t)
+	cli.SetName("app")
+	spec := cli.Spec()
+	if spec.SpecVersion != SpecVersion || spec.Name != "app" || spec.Version != "1.2.3" {
+		t.Fatalf("unexpected spec header %+v", spec)
+	}
+	if len(spec.Subcommands) == 0 || spec.Subcommands[0].Name != "greet" {
+		t.Fatalf("unexpected subcommands %+v", spec.Subcommands)
+	}
+	for _, subcmd := range spec.Subcommands {
+		if subcmd.Name == "gen-man" {
+			t.Fatalf("hidden subcommands should be left out")
+		}
+	}
+	greet := spec.Subcommands[0]
+	if len(greet.Labels) != 2 {
+		t.Fatalf("unexpected labels %+v", greet.Labels)
+	}
+	name, times := greet.Labels[0], greet.Labels[1]
+	if name.Name != "name" || len(name.Aliases) != 2 || name.Type != "str" || !name.Required || name.Default != nil {
+		t.Fatalf("unexpected label %+v", name)
+	}
+	if times.Name != "times" || times.Type != "int" || times.Required || times.Default != 1 {
+		t.Fatalf("unexpected label %+v", times)
+	}
+}
+
+func TestHelpJSON(t *testing.T) {
+	cli := sampleCli(t)
+	code, out, errOut := execute(&cli, "help", "--json")
+	if code != 0 {
+		t.Fatalf("unexpected failure %q", errOut)
+	}
+	var spec Spec
+	if err := json.Unmarshal([]byte(out), &spec); err != nil {
+		t.Fatalf("invalid JSON: %s\n%s", err, out)
+	}
+	if spec.Name != "app" || spec.SpecVersion != SpecVersion {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+	code, out, _ = execute(&cli, "help", "--json", "greet")
+	var greet map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &greet); code != 0 || err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	labels := greet["labels"].([]interface{})
+	times := labels[1].(map[string]interface{})
+	if greet["name"] != "greet" || times["default"] != 1.0 || times["required"] != false {
+		t.Fatalf("unexpected subcommand spec %s", out)
+	}
+	if code, _, _ := execute(&cli, "help", "greet", "--json"); code != 0 {
+		t.Fatalf("--json should be allowed after the subcommand")
+	}
+	code, _, errOut = execute(&cli, "help", "--json", "gret")
+	if code != 2 || errOut == "" {
+		t.Fatalf("unexpected result for an unknown subcommand: %d %q", code, errOut)
+	}
+}
@@ -12,15 +12,27 @@ type subcommandExample struct {
 	command string
 	// the expected output of the command, which can be fabricated as an example
 	output string
+	// whether the command is a format for the program name, as in the
+	// examples of the built-in subcommands
+	withProg bool
 }
 
-// Print the help message associated with the command example.
-func (ex *subcommandExample) helpMessage() string {
+// Get the text of the command, where `prog` is the program name.
+func (ex *subcommandExample) commandLine(prog string) string {
+	if ex.withProg {
+		return fmt.Sprintf(ex.command, prog)
+	}
+	return ex.command
+}
+
+// Print the help message associated with the command example, where `prog`
+// is the program name.
+func (ex *subcommandExample) helpMessage(prog string) string {
 	ret := ""
 	if len(ex.documentation) > 0 {
 		ret = ret + fmt.Sprintf("$ # %s\n", ex.documentation)
 	}
-	ret = ret + fmt.Sprintf("$ %s\n", ex.command)
+	ret = ret + fmt.Sprintf("$ %s\n", ex.commandLine(prog))
 	if len(ex.output) > 0 {
 		ret = ret + fmt.Sprintf("%s\n\n", ex.output)
 	}
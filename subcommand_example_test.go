@@ -11,7 +11,7 @@ func TestSubcommandExample(t *testing.T) {
 		command:       "grep -r \"hello world\" .",
 		output:        "",
 	}
-	m := sc.helpMessage()
+	m := sc.helpMessage("app")
 	if !strings.Contains(m, sc.documentation) {
 		t.Fail()
 	}
@@ -29,7 +29,7 @@ func TestNoHash(t *testing.T) {
 		command:       "grep -r \"hello world\" .",
 		output:        "",
 	}
-	m := sc.helpMessage()
+	m := sc.helpMessage("app")
 	if strings.Contains(m, "#") {
 		t.Fail()
 	}
@@ -1,8 +1,12 @@
 package goldcmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"unicode"
 )
 
@@ -17,14 +21,60 @@ type SubcommandHandler struct {
 	name string
 	// documentation for the subcommand
 	documentation string
-	// the actual function to handle the execution of the function
+	// the actual function to handle the execution of the function, or nil if
+	// it was not set
 	handle func(h *SubcommandHandler)
+	// the child subcommands e.g. 'migrate' in 'app db migrate'
+	subcommands []*SubcommandHandler
+	// if set, the subcommand is left out of help, completion and documentation
+	hidden bool
 	// examples of the subcommand in use
 	examples []subcommandExample
 	// the argument parsing handler
 	argparser *commandParser
 	// the param parsing handler
 	paramparser *commandParser
+	// the positional arguments, in the order they are filled
+	positionals []*positional
+	// the parser holding the values of the (non-variadic) positional arguments
+	posparser *commandParser
+	// the values of the variadic positional argument, if there is one
+	variadicValues map[string][]string
+	// the shell completion functions, by label alias or positional name
+	completers map[string]CompletionFunc
+	// the function used to look up the environment variables bound to labels
+	lookupEnv func(key string) (string, bool)
+	// the values of the parameters read from configuration files, lowest
+	// precedence first
+	config []configValue
+	// if set, the values and their sources are printed instead of running
+	// the handler function
+	showSources bool
+	// the index of each token of the parsed command line in the command line
+	// given to Cli.Execute, or nil if they are the same
+	tokenIndex []int
+
+	// The context and streams of the current execution. They default to the
+	// process streams when the handler is not run through Cli.Execute.
+	ctx    context.Context
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// the name the CLI was invoked with and the command words leading to
+	// the subcommand, for the current execution
+	path []string
+	// the exit status reported once the handler function returns
+	exitCode int
+	// unknown flags, collected when the Cli allows them
+	unknownFlags []string
+	// if set, option parsing stops at the first positional value
+	stopAtFirstNonOption bool
+	// if set, single character aliases can be clustered e.g. '-xzvf'
+	clusterShortFlags bool
+	// the values after the end of the options that no positional argument took
+	remainingArgs []string
+	// the groups of parameters and the rules they follow
+	groups []labelGroup
 }
 
 // Check that a flag name is valid.
@@ -38,11 +88,9 @@ func aliasIsValid(name string) bool {
 		if i == 0 {
 			if !unicode.IsLetter(c) {
 				return false
-			} else {
-				if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '-' {
-					return false
-				}
 			}
+		} else if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '-' {
+			return false
 		}
 	}
 	return true
@@ -58,24 +106,41 @@ func NewSubcommandHandler(name string, doc string) (*SubcommandHandler, error) {
 	return &SubcommandHandler{
 		name:          name,
 		documentation: doc,
-		handle:        func(h *SubcommandHandler) {},
+		subcommands:   make([]*SubcommandHandler, 0),
 		examples:      make([]subcommandExample, 0),
 		argparser:     newCommandParser(),
 		paramparser:   newCommandParser(),
+		positionals:   make([]*positional, 0),
+		posparser:     newCommandParser(),
+		completers:    make(map[string]CompletionFunc),
+		groups:        make([]labelGroup, 0),
+		lookupEnv:     os.LookupEnv,
+		ctx:           context.Background(),
+		stdin:         os.Stdin,
+		stdout:        os.Stdout,
+		stderr:        os.Stderr,
 	}, nil
 }
 
 // Check if a set of aliases are valid and not already in use.
-// The function returns true if any of the aliases can be used.
+// The function returns true if all of the aliases can be used.
 func (h *SubcommandHandler) checkAliasesAllowed(aliases []string) bool {
-	for _, alias := range aliases {
+	if len(aliases) == 0 {
+		return false
+	}
+	for i, alias := range aliases {
 		if !aliasIsValid(alias) {
 			return false
 		}
+		for _, other := range aliases[:i] {
+			if alias == other {
+				return false
+			}
+		}
 	}
 	// check that the label is not in use
 	for _, alias := range aliases {
-		if h.argparser.hasAlias(alias) || h.paramparser.hasAlias(alias) {
+		if h.argparser.hasAlias(alias) || h.paramparser.hasAlias(alias) || h.posparser.hasAlias(alias) {
 			return false
 		}
 	}
@@ -83,7 +148,8 @@ func (h *SubcommandHandler) checkAliasesAllowed(aliases []string) bool {
 }
 
 // Add an integer argument to the subcommand.
-// Warning, the subcommand will always fail if the argument is not set.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
 // If one of the aliases is used by another argument or
 // parameter, the function will return an error and handler will not be mutated.
 func (h *SubcommandHandler) AddIntArg(aliases []string, doc string) error {
@@ -100,7 +166,8 @@ func (h *SubcommandHandler) AddIntArg(aliases []string, doc string) error {
 }
 
 // Add a float argument to the subcommand.
-// Warning, the subcommand will always fail if the argument is not set.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
 // If one of the aliases is used by another argument or
 // parameter, the function will return an error and handler will not be mutated.
 func (h *SubcommandHandler) AddFloatArg(aliases []string, doc string) error {
@@ -112,7 +179,8 @@ func (h *SubcommandHandler) AddFloatArg(aliases []string, doc string) error {
 }
 
 // Add a string argument to the subcommand.
-// Warning, the subcommand will always fail if the argument is not set.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
 // If one of the aliases is used by another argument or
 // parameter, the function will return an error and handler will not be mutated.
 func (h *SubcommandHandler) AddStrArg(aliases []string, doc string) error {
@@ -124,7 +192,8 @@ func (h *SubcommandHandler) AddStrArg(aliases []string, doc string) error {
 }
 
 // Add a boolean argument to the subcommand.
-// Warning, the subcommand will always fail if the argument is not set.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
 // If one of the aliases is used by another argument or
 // parameter, the function will return an error and handler will not be mutated.
 func (h *SubcommandHandler) AddBoolArg(aliases []string, doc string) error {
@@ -140,8 +209,8 @@ func (h *SubcommandHandler) AddIntParamWithDefault(aliases []string, doc string,
 	if !h.checkAliasesAllowed(aliases) {
 		return errors.New("invalid label value")
 	}
-	h.paramparser.addIntArg(aliases, doc)
-	h.paramparser.setIntArg(aliases, deflt)
+	value := intValue(deflt)
+	h.paramparser.addLabelWithDefault(aliases, doc, &value)
 	return nil
 }
 
@@ -150,8 +219,8 @@ func (h *SubcommandHandler) AddFloatParamWithDefault(aliases []string, doc strin
 	if !h.checkAliasesAllowed(aliases) {
 		return errors.New("invalid label value")
 	}
-	h.paramparser.addFloatArg(aliases, doc)
-	h.paramparser.setFloatArg(aliases, deflt)
+	value := floatValue(deflt)
+	h.paramparser.addLabelWithDefault(aliases, doc, &value)
 	return nil
 }
 
@@ -160,8 +229,8 @@ func (h *SubcommandHandler) AddStrParamWithDefault(aliases []string, doc string,
 	if !h.checkAliasesAllowed(aliases) {
 		return errors.New("invalid label value")
 	}
-	h.paramparser.addStrArg(aliases, doc)
-	h.paramparser.setStrArg(aliases, deflt)
+	value := strValue(deflt)
+	h.paramparser.addLabelWithDefault(aliases, doc, &value)
 	return nil
 }
 
@@ -170,8 +239,8 @@ func (h *SubcommandHandler) AddBoolParamWithDefault(aliases []string, doc string
 	if !h.checkAliasesAllowed(aliases) {
 		return errors.New("invalid label value")
 	}
-	h.paramparser.addBoolArg(aliases, doc)
-	h.paramparser.setBoolArg(aliases, deflt)
+	value := boolValue(deflt)
+	h.paramparser.addLabelWithDefault(aliases, doc, &value)
 	return nil
 }
 
@@ -184,6 +253,31 @@ func (h *SubcommandHandler) Example(doc string, cmd string, out string) {
 		documentation: doc, command: cmd, output: out})
 }
 
+// Add a child subcommand, making this subcommand a command group. For example,
+// if this subcommand is 'db' and the child is 'migrate', then the child is run
+// with 'app db migrate'. Groups can be nested to any depth.
+//
+// A group can still have its own handler function, which is run when no child
+// is named. Flags are parsed from after the deepest command word.
+func (h *SubcommandHandler) HandleSubcommand(child *SubcommandHandler) {
+	h.subcommands = append(h.subcommands, child)
+}
+
+// Hide the subcommand from help messages, shell completion and generated
+// documentation. A hidden subcommand can still be run.
+func (h *SubcommandHandler) SetHidden(hidden bool) {
+	h.hidden = hidden
+}
+
+// Get the names of the visible child subcommands.
+func (h *SubcommandHandler) subcommandNames() []string {
+	names := make([]string, 0, len(h.subcommands))
+	for _, child := range visible(h.subcommands) {
+		names = append(names, child.name)
+	}
+	return names
+}
+
 // Set the handler function for a SubcommandHandler instance.
 // # Arguments
 // - sub: a SubcommandHandler instance to be mutated
@@ -196,10 +290,7 @@ func (h *SubcommandHandler) Handle(f func(insub *SubcommandHandler)) {
 // Warning: This function will fail if the command line arguments have
 // not already been parsed.
 func (h *SubcommandHandler) GetInt(key string) (int, error) {
-	if val, b := h.argparser.intValues[key]; b {
-		return val, nil
-	}
-	if val, b := h.paramparser.intValues[key]; b {
+	if val, b := h.get(key).(int); b {
 		return val, nil
 	}
 	return 0, errors.New("key not available")
@@ -209,10 +300,7 @@ func (h *SubcommandHandler) GetInt(key string) (int, error) {
 // Warning: This function will fail if the command line arguments have
 // not already been parsed.
 func (h *SubcommandHandler) GetStr(key string) (string, error) {
-	if val, b := h.argparser.strValues[key]; b {
-		return val, nil
-	}
-	if val, b := h.paramparser.strValues[key]; b {
+	if val, b := h.get(key).(string); b {
 		return val, nil
 	}
 	return "", errors.New("key not available")
@@ -222,10 +310,7 @@ func (h *SubcommandHandler) GetStr(key string) (string, error) {
 // Warning: This function will fail if the command line arguments have
 // not already been parsed.
 func (h *SubcommandHandler) GetFloat(key string) (float64, error) {
-	if val, b := h.argparser.floatValues[key]; b {
-		return val, nil
-	}
-	if val, b := h.paramparser.floatValues[key]; b {
+	if val, b := h.get(key).(float64); b {
 		return val, nil
 	}
 	return 0.0, errors.New("key not available")
@@ -235,43 +320,260 @@ func (h *SubcommandHandler) GetFloat(key string) (float64, error) {
 // Warning: This function will fail if the command line arguments have
 // not already been parsed.
 func (h *SubcommandHandler) GetBool(key string) (val bool, err error) {
-	if val, b := h.argparser.boolValues[key]; b {
-		return val, nil
-	}
-	if val, b := h.paramparser.boolValues[key]; b {
+	if val, b := h.get(key).(bool); b {
 		return val, nil
 	}
 	return false, errors.New("key not available")
 }
 
-// Parse the command line flags.
-func (h *SubcommandHandler) parseFlags(args []string) {
-	h.argparser.parseFlags(args)
-	h.paramparser.parseFlags(args)
+// Get the context of the current execution.
+func (h *SubcommandHandler) Context() context.Context {
+	return h.ctx
+}
+
+// Get the stream the handler should read input from.
+func (h *SubcommandHandler) Stdin() io.Reader {
+	return h.stdin
+}
+
+// Get the stream the handler should write output to.
+func (h *SubcommandHandler) Stdout() io.Writer {
+	return h.stdout
+}
+
+// Get the stream the handler should write errors to.
+func (h *SubcommandHandler) Stderr() io.Writer {
+	return h.stderr
+}
+
+// Set the exit status returned by Cli.Execute once the handler function
+// returns. The status is 0 unless this is called.
+func (h *SubcommandHandler) SetExitCode(code int) {
+	h.exitCode = code
+}
+
+// Get the flags that the subcommand does not know about, in the order they
+// were given. Unknown flags are only collected if the Cli allows them,
+// otherwise they are errors.
+func (h *SubcommandHandler) UnknownFlags() []string {
+	return h.unknownFlags
+}
+
+// Stop parsing flags at the first positional value, in the style of
+// POSIXLY_CORRECT, so that the value and everything after it is treated as
+// if it followed "--". This is useful for subcommands that wrap other
+// programs, e.g. 'app exec ls -la'.
+func (h *SubcommandHandler) StopAtFirstNonOption(stop bool) {
+	h.stopAtFirstNonOption = stop
+}
+
+// Parse flags in the GNU/POSIX style, where a token with one dash is a
+// cluster of single character aliases, e.g. '-xzvf archive.tar' is the same
+// as '-x -z -v -f archive.tar'. Boolean flags can be clustered, and the last
+// flag of a cluster can take the rest of the token as its value, e.g. '-n5',
+// or the next token. Long aliases must be given with two dashes, e.g.
+// '--count 5', and help shows single character aliases with one dash.
+func (h *SubcommandHandler) ClusterShortFlags(cluster bool) {
+	h.clusterShortFlags = cluster
+	h.argparser.shortFlags = cluster
+	h.paramparser.shortFlags = cluster
 }
 
-// Print argument documentation.
-func (h *SubcommandHandler) printArgumentHelp() {
-	s := h.argparser.helpString()
+// Get the values after the end of the options that were not taken by a
+// positional argument, verbatim. The options end at "--" or, if
+// StopAtFirstNonOption is set, at the first positional value. If the Cli
+// allows unknown flags, the values left over before the end of the options
+// are kept too, in order, since they may be the values of unknown flags.
+// For example, 'app run -- go test -v ./...' leaves ["go", "test", "-v",
+// "./..."] for a subcommand without positional arguments.
+func (h *SubcommandHandler) RemainingArgs() []string {
+	return h.remainingArgs
+}
+
+// Get the index, in the original command line, of every unknown flag that
+// the subcommand would parse in a command line, from index `start`, just
+// after the command words. These are the tokens that can be global flags,
+// so '--show-config' after the first operand of a subcommand that stops at
+// it is left alone.
+func (h *SubcommandHandler) unknownFlagIndices(cl *commandLine, start int) map[int]bool {
+	at := make([]int, 0)
+	scanFlags(cl.args, h.parsers(), parseOptions{start: start, allowUnknown: true, unknownAt: &at,
+		stopAtOperand: h.stopAtFirstNonOption, clusterShort: h.clusterShortFlags})
+	// the scan sets the values it finds, which parseFlags sets again
+	h.argparser.reset()
+	h.paramparser.reset()
+	indices := make(map[int]bool)
+	for _, k := range at {
+		indices[cl.index[k]] = true
+	}
+	return indices
+}
+
+// Get the parsers for the flags of the subcommand.
+func (h *SubcommandHandler) parsers() []*commandParser {
+	return []*commandParser{h.argparser, h.paramparser}
+}
+
+// Parse the command line flags and positional arguments, starting at index
+// `start` of `args`, just after the command words.
+// Every value that could not be used, every unknown flag and every unexpected
+// positional value (as a *ParseError) and every missing argument is reported
+// in the returned ErrorList. If `allowUnknown` is set, unknown flags are
+// collected instead.
+func (h *SubcommandHandler) parseFlags(args []string, start int, allowUnknown bool) error {
+	h.argparser.reset()
+	h.paramparser.reset()
+	h.posparser.reset()
+	h.parseConfig()
+	h.unknownFlags = make([]string, 0)
+	operands := make([]int, 0)
+	end := len(args)
+	opts := parseOptions{start: start, allowUnknown: allowUnknown, unknown: &h.unknownFlags, operands: &operands,
+		stopAtOperand: h.stopAtFirstNonOption, endOfOptions: &end, clusterShort: h.clusterShortFlags}
+	errs := make(ErrorList, 0)
+	errs = append(errs, scanFlags(args, h.parsers(), opts)...)
+	errs = append(errs, h.assignPositionals(args, operands, end, allowUnknown)...)
+	errs = append(errs, h.argparser.parseEnv(h.lookupEnv)...)
+	errs = append(errs, h.paramparser.parseEnv(h.lookupEnv)...)
+	errs = append(errs, h.argparser.checkAllSet(errs)...)
+	errs = append(errs, h.argparser.checkCounts()...)
+	errs = append(errs, h.paramparser.checkCounts()...)
+	errs = append(errs, h.checkValidators()...)
+	errs = append(errs, h.checkGroups()...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Get the usage line for the subcommand, where `path` holds the name the CLI
+// was invoked with and the command words leading to the subcommand.
+func (h *SubcommandHandler) usage(path []string) string {
+	s := "usage: " + strings.Join(path, " ")
+	if len(h.subcommands) > 0 {
+		if h.handle == nil {
+			s = s + " <subcommand>"
+		} else {
+			s = s + " [subcommand]"
+		}
+	}
+	if args := h.argparser.usageString(); len(args) > 0 {
+		s = s + " " + args
+	}
+	for _, g := range h.groups {
+		s = s + " " + h.groupUsage(g)
+	}
+	if len(h.paramparser.allLabels) > 0 {
+		s = s + " [OPTIONS]"
+	}
+	for _, p := range h.positionals {
+		s = s + " " + p.usageToken()
+	}
+	return s
+}
+
+// Print a usage error to `w`, and return the exit status for it.
+func (h *SubcommandHandler) usageError(w io.Writer, path []string, err error) int {
+	if list, ok := err.(ErrorList); ok {
+		for _, e := range list {
+			if pe, ok := e.(*ParseError); ok {
+				fmt.Fprintf(w, "error: %s\n", pe.Render())
+			} else {
+				fmt.Fprintf(w, "error: %s\n", e)
+			}
+		}
+	} else {
+		fmt.Fprintf(w, "error: %s\n", err)
+	}
+	fmt.Fprintf(w, "%s\n", h.usage(path))
+	fmt.Fprintf(w, "Run '%s help %s' for more information.\n", path[0], strings.Join(path[1:], " "))
+	return 2
+}
+
+// Parse the command line, run the handler function with the given context
+// and streams, and return the exit status. The argument `path` holds the
+// name the CLI was invoked with and the command words leading to the
+// subcommand, so flags are parsed from index len(path) of `args`.
+// If the command line is not valid, the errors and the usage of the
+// subcommand are printed to `stderr` and the handler is not run.
+func (h *SubcommandHandler) execute(ctx context.Context, args []string, path []string, allowUnknown bool, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	h.ctx = ctx
+	h.stdin = stdin
+	h.stdout = stdout
+	h.stderr = stderr
+	h.path = path
+	h.exitCode = 0
+	if h.handle == nil && len(h.subcommands) > 0 {
+		return h.usageError(stderr, path, errors.New("missing subcommand"))
+	}
+	// parse command line arguments and pass them to the subcommand
+	if err := h.parseFlags(args, len(path), allowUnknown); err != nil {
+		return h.usageError(stderr, path, err)
+	}
+	if h.showSources {
+		h.printSources(stdout)
+		return 0
+	}
+	// if the values are valid, then run the subcommand's handle function
+	if h.handle != nil {
+		h.handle(h)
+	}
+	return h.exitCode
+}
+
+// Print the help message for the subcommand to `w`, where `path` holds the
+// name the CLI was invoked with and the command words leading to the
+// subcommand.
+func (h *SubcommandHandler) printHelp(w io.Writer, path []string) {
+	fmt.Fprintf(w, "%s\n\n", h.documentation)
+	fmt.Fprintf(w, "%s\n\n", h.usage(path))
+	h.printSubcommandHelp(w)
+	h.printArgumentHelp(w)
+	h.printOptionHelp(w)
+	h.printExampleHelp(w, path[0])
+}
+
+// Print child subcommand documentation.
+func (h *SubcommandHandler) printSubcommandHelp(w io.Writer) {
+	if children := visible(h.subcommands); len(children) > 0 {
+		fmt.Fprintf(w, "SUBCOMMANDS\n")
+		for _, child := range children {
+			fmt.Fprintf(w, "  %s\t%s\n", child.name, child.documentation)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+// Print argument documentation, for the positional arguments then the
+// required flags.
+func (h *SubcommandHandler) printArgumentHelp(w io.Writer) {
+	s := ""
+	for _, p := range h.positionals {
+		s = s + p.helpString(h.posparser.rules(p.name))
+		if choices := h.choiceOf(p.name); choices != nil {
+			s = s + choices.helpString()
+		}
+	}
+	s = s + h.argparser.helpString()
 	if len(s) > 0 {
-		fmt.Printf("ARGUMENTS\n%s\n\n", s)
+		fmt.Fprintf(w, "ARGUMENTS\n%s\n\n", s)
 	}
 }
 
 // Print option documentation.
-func (h *SubcommandHandler) printOptionHelp() {
+func (h *SubcommandHandler) printOptionHelp(w io.Writer) {
 	s := h.paramparser.helpString()
 	if len(s) > 0 {
-		fmt.Printf("OPTIONS\n%s\n\n", s)
+		fmt.Fprintf(w, "OPTIONS\n%s\n\n", s)
 	}
 }
 
-// Print example documentation.
-func (h *SubcommandHandler) printExampleHelp() {
+// Print example documentation, where `prog` is the program name.
+func (h *SubcommandHandler) printExampleHelp(w io.Writer, prog string) {
 	if len(h.examples) > 0 {
-		fmt.Printf("EXAMPLES\n")
+		fmt.Fprintf(w, "EXAMPLES\n")
 		for _, ex := range h.examples {
-			fmt.Print(ex.helpMessage())
+			fmt.Fprint(w, ex.helpMessage(prog))
 		}
 	}
 }
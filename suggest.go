@@ -0,0 +1,48 @@
+package goldcmd
+
+// Get the edit (Levenshtein) distance between two strings.
+func editDistance(a string, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Get the candidate closest to a mistyped word, or an empty string if no
+// candidate is close enough to be a plausible suggestion.
+func suggest(word string, candidates []string) string {
+	// allow roughly one typo for every three characters
+	best := len([]rune(word))/3 + 1
+	if best > 3 {
+		best = 3
+	}
+	ret := ""
+	for _, c := range candidates {
+		if d := editDistance(word, c); d <= best && (ret == "" || d < best) {
+			best = d
+			ret = c
+		}
+	}
+	return ret
+}
@@ -0,0 +1,36 @@
+package goldcmd
+
+import (
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"second", "secnd", 1},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+	}
+	for _, c := range cases {
+		if d := editDistance(c.a, c.b); d != c.d {
+			t.Fatalf("distance between %q and %q should be %d, got %d", c.a, c.b, c.d, d)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	labels := []string{"first", "f", "second", "s"}
+	if s := suggest("secnd", labels); s != "second" {
+		t.Fatalf("expected \"second\", got %q", s)
+	}
+	if s := suggest("frist", labels); s != "first" {
+		t.Fatalf("expected \"first\", got %q", s)
+	}
+	if s := suggest("verbose", labels); s != "" {
+		t.Fatalf("expected no suggestion, got %q", s)
+	}
+}
@@ -0,0 +1,541 @@
+package goldcmd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file holds the built-in values for types that are commonly given on
+// the command line, beyond numbers and strings. Their errors say what a
+// valid value looks like, rather than repeating the error of the parsing
+// function. Values whose default cannot be given as text, like a nil URL,
+// keep their default and restore it with Reset.
+
+// The value of a duration label e.g. "30s" or "1h30m".
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("invalid duration, e.g. 30s or 1h30m")
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) Type() string   { return "duration" }
+
+// The layouts a time is parsed with, if no others are given.
+var defaultTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// The value of a time label, which is parsed with the first layout that
+// matches and formatted with the first layout. The default is kept as it
+// is, since formatting it with the layout may lose e.g. the time of day.
+type timeValue struct {
+	t       time.Time
+	deflt   time.Time
+	layouts []string
+}
+
+// Create a time value with the given layouts, or the default layouts if
+// there are none.
+func newTimeValue(t time.Time, layouts []string) *timeValue {
+	if len(layouts) == 0 {
+		layouts = defaultTimeLayouts
+	}
+	return &timeValue{t: t, deflt: t, layouts: append([]string{}, layouts...)}
+}
+
+func (v *timeValue) Set(s string) error {
+	for _, layout := range v.layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			v.t = t
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time, expected the layout %s", strings.Join(v.layouts, " or "))
+}
+
+func (v *timeValue) String() string { return v.t.Format(v.layouts[0]) }
+func (v *timeValue) Type() string   { return "time" }
+func (v *timeValue) Reset()         { v.t = v.deflt }
+func (v *timeValue) keepDefault()   { v.deflt = v.t }
+
+// The value of a URL label. The URL must be absolute, e.g.
+// "https://example.com".
+type urlValue struct {
+	u     *url.URL
+	deflt *url.URL
+}
+
+func (v *urlValue) Set(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("invalid URL: %s", err)
+	}
+	if u.Scheme == "" {
+		return errors.New("invalid URL: missing scheme, e.g. https://example.com")
+	}
+	v.u = u
+	return nil
+}
+
+func (v *urlValue) String() string {
+	if v.u == nil {
+		return ""
+	}
+	return v.u.String()
+}
+
+func (v *urlValue) Type() string { return "url" }
+func (v *urlValue) Reset()       { v.u = v.deflt }
+func (v *urlValue) keepDefault() { v.deflt = v.u }
+
+// The value of an IP address label e.g. "127.0.0.1" or "::1". The zero
+// value is not valid, and is formatted as an empty string.
+type addrValue struct {
+	addr  netip.Addr
+	deflt netip.Addr
+}
+
+func (v *addrValue) Set(s string) error {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return errors.New("invalid IP address, e.g. 127.0.0.1 or ::1")
+	}
+	v.addr = addr
+	return nil
+}
+
+func (v *addrValue) String() string {
+	if !v.addr.IsValid() {
+		return ""
+	}
+	return v.addr.String()
+}
+
+func (v *addrValue) Type() string { return "ip" }
+func (v *addrValue) Reset()       { v.addr = v.deflt }
+func (v *addrValue) keepDefault() { v.deflt = v.addr }
+
+// The value of an IP address and port label e.g. "127.0.0.1:8080". The
+// zero value is not valid, and is formatted as an empty string.
+type addrPortValue struct {
+	addrPort netip.AddrPort
+	deflt    netip.AddrPort
+}
+
+func (v *addrPortValue) Set(s string) error {
+	addrPort, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return errors.New("invalid IP address and port, e.g. 127.0.0.1:8080 or [::1]:8080")
+	}
+	v.addrPort = addrPort
+	return nil
+}
+
+func (v *addrPortValue) String() string {
+	if !v.addrPort.IsValid() {
+		return ""
+	}
+	return v.addrPort.String()
+}
+
+func (v *addrPortValue) Type() string { return "ip:port" }
+func (v *addrPortValue) Reset()       { v.addrPort = v.deflt }
+func (v *addrPortValue) keepDefault() { v.deflt = v.addrPort }
+
+// The value of an IP prefix label in CIDR notation e.g. "10.0.0.0/8". The
+// zero value is not valid, and is formatted as an empty string.
+type prefixValue struct {
+	prefix netip.Prefix
+	deflt  netip.Prefix
+}
+
+func (v *prefixValue) Set(s string) error {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return errors.New("invalid CIDR prefix, e.g. 10.0.0.0/8")
+	}
+	v.prefix = prefix
+	return nil
+}
+
+func (v *prefixValue) String() string {
+	if !v.prefix.IsValid() {
+		return ""
+	}
+	return v.prefix.String()
+}
+
+func (v *prefixValue) Type() string { return "cidr" }
+func (v *prefixValue) Reset()       { v.prefix = v.deflt }
+func (v *prefixValue) keepDefault() { v.deflt = v.prefix }
+
+// A unit of a byte size.
+type byteUnit struct {
+	suffix string
+	size   int64
+}
+
+// The units of byte sizes, IEC then SI, largest first.
+var byteUnits = []byteUnit{
+	{"EiB", 1 << 60}, {"PiB", 1 << 50}, {"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"EB", 1e18}, {"PB", 1e15}, {"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3},
+	{"B", 1},
+}
+
+// Parse a byte size with an optional SI or IEC suffix e.g. "512MB" or
+// "1.5GiB". The suffixes are not case-sensitive.
+func parseByteSize(s string) (int64, error) {
+	invalid := errors.New("invalid byte size, e.g. 512MB or 1.5GiB")
+	k := 0
+	for k < len(s) && (s[k] == '.' || (s[k] >= '0' && s[k] <= '9')) {
+		k++
+	}
+	n, err := strconv.ParseFloat(s[:k], 64)
+	if err != nil {
+		return 0, invalid
+	}
+	size := n
+	if suffix := strings.TrimSpace(s[k:]); suffix != "" {
+		found := false
+		for _, unit := range byteUnits {
+			if strings.EqualFold(suffix, unit.suffix) {
+				size = n * float64(unit.size)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid byte size, unknown unit %q", suffix)
+		}
+	}
+	if size >= math.MaxInt64 {
+		return 0, errors.New("invalid byte size, too large")
+	}
+	if size != math.Trunc(size) {
+		return 0, errors.New("invalid byte size, not a whole number of bytes")
+	}
+	return int64(size), nil
+}
+
+// Format a byte size with the largest unit that it is a whole number of,
+// preferring IEC units e.g. "1536MiB".
+func formatByteSize(size int64) string {
+	if size == 0 {
+		return "0B"
+	}
+	for _, unit := range byteUnits {
+		if size%unit.size == 0 {
+			return strconv.FormatInt(size/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(size, 10) + "B"
+}
+
+// The value of a byte size label, in bytes.
+type byteSizeValue int64
+
+func (v *byteSizeValue) Set(s string) error {
+	size, err := parseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*v = byteSizeValue(size)
+	return nil
+}
+
+func (v *byteSizeValue) String() string { return formatByteSize(int64(*v)) }
+func (v *byteSizeValue) Type() string   { return "size" }
+
+// The value of a regular expression label, which is compiled when it is set.
+type regexpValue struct {
+	re    *regexp.Regexp
+	deflt *regexp.Regexp
+}
+
+func (v *regexpValue) Set(s string) error {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return fmt.Errorf("invalid regular expression: %s", syntaxErr.Code)
+		}
+		return err
+	}
+	v.re = re
+	return nil
+}
+
+func (v *regexpValue) String() string {
+	if v.re == nil {
+		return ""
+	}
+	return v.re.String()
+}
+
+func (v *regexpValue) Type() string { return "regexp" }
+func (v *regexpValue) Reset()       { v.re = v.deflt }
+func (v *regexpValue) keepDefault() { v.deflt = v.re }
+
+// Get a new value for the name of one of the types in this file, or nil if
+// the name is not one of them.
+func newRichValue(typeName string, layouts []string) Value {
+	switch typeName {
+	case "duration":
+		return new(durationValue)
+	case "time":
+		return newTimeValue(time.Time{}, layouts)
+	case "url":
+		return new(urlValue)
+	case "ip":
+		return new(addrValue)
+	case "ip:port":
+		return new(addrPortValue)
+	case "cidr":
+		return new(prefixValue)
+	case "size":
+		return new(byteSizeValue)
+	case "regexp":
+		return new(regexpValue)
+	}
+	return nil
+}
+
+// Add a duration argument to the subcommand e.g. '--timeout 30s'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddDurationArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(durationValue))
+}
+
+// Add a duration parameter to the subcommand with a default value.
+func (h *SubcommandHandler) AddDurationParamWithDefault(aliases []string, doc string, deflt time.Duration) error {
+	value := durationValue(deflt)
+	return h.AddValueParam(aliases, doc, &value)
+}
+
+// Get a duration argument or parameter from the command line.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetDuration(key string) (time.Duration, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*durationValue); ok {
+			return time.Duration(*v), nil
+		}
+	}
+	return 0, errors.New("key not available")
+}
+
+// Add a time argument to the subcommand e.g. '--since 2026-01-01T00:00:00Z'.
+// The value is parsed with the first of the layouts that matches, see
+// time.Parse, and the first layout is used in help. Without layouts, RFC 3339
+// times, times without a zone, and dates are accepted.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddTimeArg(aliases []string, doc string, layouts ...string) error {
+	return h.AddValueArg(aliases, doc, newTimeValue(time.Time{}, layouts))
+}
+
+// Add a time parameter to the subcommand with a default value. The layouts
+// are used as for AddTimeArg.
+func (h *SubcommandHandler) AddTimeParamWithDefault(aliases []string, doc string, deflt time.Time, layouts ...string) error {
+	return h.AddValueParam(aliases, doc, newTimeValue(deflt, layouts))
+}
+
+// Get a time argument or parameter from the command line.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetTime(key string) (time.Time, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*timeValue); ok {
+			return v.t, nil
+		}
+	}
+	return time.Time{}, errors.New("key not available")
+}
+
+// Add a URL argument to the subcommand e.g. '--endpoint https://example.com'.
+// The URL must have a scheme.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddURLArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(urlValue))
+}
+
+// Add a URL parameter to the subcommand with a default value, which may be
+// nil.
+func (h *SubcommandHandler) AddURLParamWithDefault(aliases []string, doc string, deflt *url.URL) error {
+	return h.AddValueParam(aliases, doc, &urlValue{u: deflt, deflt: deflt})
+}
+
+// Get a URL argument or parameter from the command line. The URL should
+// not be changed, as it is shared with later calls.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetURL(key string) (*url.URL, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*urlValue); ok {
+			return v.u, nil
+		}
+	}
+	return nil, errors.New("key not available")
+}
+
+// Add an IP address argument to the subcommand e.g. '--bind 127.0.0.1'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddAddrArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(addrValue))
+}
+
+// Add an IP address parameter to the subcommand with a default value.
+func (h *SubcommandHandler) AddAddrParamWithDefault(aliases []string, doc string, deflt netip.Addr) error {
+	return h.AddValueParam(aliases, doc, &addrValue{addr: deflt, deflt: deflt})
+}
+
+// Get an IP address argument or parameter from the command line.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetAddr(key string) (netip.Addr, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*addrValue); ok {
+			return v.addr, nil
+		}
+	}
+	return netip.Addr{}, errors.New("key not available")
+}
+
+// Add an IP address and port argument to the subcommand e.g.
+// '--listen 127.0.0.1:8080'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddAddrPortArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(addrPortValue))
+}
+
+// Add an IP address and port parameter to the subcommand with a default
+// value.
+func (h *SubcommandHandler) AddAddrPortParamWithDefault(aliases []string, doc string, deflt netip.AddrPort) error {
+	return h.AddValueParam(aliases, doc, &addrPortValue{addrPort: deflt, deflt: deflt})
+}
+
+// Get an IP address and port argument or parameter from the command line.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetAddrPort(key string) (netip.AddrPort, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*addrPortValue); ok {
+			return v.addrPort, nil
+		}
+	}
+	return netip.AddrPort{}, errors.New("key not available")
+}
+
+// Add an IP prefix argument in CIDR notation to the subcommand e.g.
+// '--subnet 10.0.0.0/8'.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddPrefixArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(prefixValue))
+}
+
+// Add an IP prefix parameter to the subcommand with a default value.
+func (h *SubcommandHandler) AddPrefixParamWithDefault(aliases []string, doc string, deflt netip.Prefix) error {
+	return h.AddValueParam(aliases, doc, &prefixValue{prefix: deflt, deflt: deflt})
+}
+
+// Get an IP prefix argument or parameter from the command line.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetPrefix(key string) (netip.Prefix, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*prefixValue); ok {
+			return v.prefix, nil
+		}
+	}
+	return netip.Prefix{}, errors.New("key not available")
+}
+
+// Add a byte size argument to the subcommand e.g. '--max-size 1.5GiB'.
+// Sizes can have an SI suffix (kB, MB, GB, TB, PB, EB), an IEC suffix
+// (KiB, MiB, GiB, TiB, PiB, EiB) or B, in any case, and are read in bytes.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddByteSizeArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(byteSizeValue))
+}
+
+// Add a byte size parameter to the subcommand with a default value in bytes.
+func (h *SubcommandHandler) AddByteSizeParamWithDefault(aliases []string, doc string, deflt int64) error {
+	value := byteSizeValue(deflt)
+	return h.AddValueParam(aliases, doc, &value)
+}
+
+// Get a byte size argument or parameter from the command line, in bytes.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetByteSize(key string) (int64, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*byteSizeValue); ok {
+			return int64(*v), nil
+		}
+	}
+	return 0, errors.New("key not available")
+}
+
+// Add a regular expression argument to the subcommand e.g. '--match ^foo'.
+// The expression is compiled when it is parsed, see regexp.Compile.
+// The argument is required: if it is not set, the subcommand fails without
+// running its handler function.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddRegexpArg(aliases []string, doc string) error {
+	return h.AddValueArg(aliases, doc, new(regexpValue))
+}
+
+// Add a regular expression parameter to the subcommand with a default value,
+// which may be nil.
+func (h *SubcommandHandler) AddRegexpParamWithDefault(aliases []string, doc string, deflt *regexp.Regexp) error {
+	return h.AddValueParam(aliases, doc, &regexpValue{re: deflt, deflt: deflt})
+}
+
+// Get a regular expression argument or parameter from the command line.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetRegexp(key string) (*regexp.Regexp, error) {
+	if value, err := h.GetValue(key); err == nil {
+		if v, ok := value.(*regexpValue); ok {
+			return v.re, nil
+		}
+	}
+	return nil, errors.New("key not available")
+}
@@ -0,0 +1,194 @@
+package goldcmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleTypesCli(t *testing.T) Cli {
+	cli := NewCli("1.0", "A CLI with rich value types.")
+	sub, _ := NewSubcommandHandler("serve", "Serve something.")
+	for _, err := range []error{
+		sub.AddDurationParamWithDefault([]string{"timeout"}, "how long to wait", 30*time.Second),
+		sub.AddTimeArg([]string{"since"}, "when to start", "2006-01-02", time.RFC3339),
+		sub.AddURLParamWithDefault([]string{"endpoint"}, "where to report", nil),
+		sub.AddAddrPortParamWithDefault([]string{"listen"}, "where to listen", netip.MustParseAddrPort("127.0.0.1:8080")),
+		sub.AddAddrParamWithDefault([]string{"bind"}, "the address to bind", netip.Addr{}),
+		sub.AddPrefixParamWithDefault([]string{"subnet"}, "the allowed subnet", netip.MustParsePrefix("10.0.0.0/8")),
+		sub.AddByteSizeParamWithDefault([]string{"max-size"}, "the largest request", 1<<20),
+		sub.AddRegexpParamWithDefault([]string{"match"}, "the paths to serve", regexp.MustCompile("^/")),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sub.Handle(func(h *SubcommandHandler) {
+		timeout, _ := h.GetDuration("timeout")
+		since, _ := h.GetTime("since")
+		endpoint, _ := h.GetURL("endpoint")
+		listen, _ := h.GetAddrPort("listen")
+		bind, _ := h.GetAddr("bind")
+		subnet, _ := h.GetPrefix("subnet")
+		size, _ := h.GetByteSize("max-size")
+		match, _ := h.GetRegexp("match")
+		fmt.Fprintf(h.Stdout(), "%s %s %v %s %s %s %d %s", timeout, since.Format(time.RFC3339), endpoint, listen, bind, subnet, size, match)
+	})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func TestRichTypes(t *testing.T) {
+	cli := sampleTypesCli(t)
+	code, out, errOut := execute(&cli, "serve", "--since", "2026-01-01")
+	if want := "30s 2026-01-01T00:00:00Z <nil> 127.0.0.1:8080 invalid IP 10.0.0.0/8 1048576 ^/"; code != 0 || out != want {
+		t.Fatalf("expected %q, got %d %q %q", want, code, out, errOut)
+	}
+	code, out, errOut = execute(&cli, "serve", "--since", "2026-01-01T10:00:00+01:00", "--timeout", "1m30s",
+		"--endpoint", "https://example.com/x", "--listen", "[::1]:80", "--bind", "::1", "--subnet", "192.168.0.0/16",
+		"--max-size", "1.5GiB", "--match", "^foo")
+	if want := "1m30s 2026-01-01T10:00:00+01:00 https://example.com/x [::1]:80 ::1 192.168.0.0/16 1610612736 ^foo"; code != 0 || out != want {
+		t.Fatalf("expected %q, got %d %q %q", want, code, out, errOut)
+	}
+	if _, out, _ := execute(&cli, "serve", "--since", "2026-01-01"); !strings.HasPrefix(out, "30s 2026-01-01T00:00:00Z <nil>") {
+		t.Fatalf("the defaults should be restored, got %q", out)
+	}
+}
+
+func TestRichTypeErrors(t *testing.T) {
+	cli := sampleTypesCli(t)
+	tests := []struct {
+		flag  string
+		value string
+		err   string
+	}{
+		{"timeout", "soon", "expected duration: invalid duration, e.g. 30s or 1h30m"},
+		{"since", "yesterday", "expected time: invalid time, expected the layout 2006-01-02 or 2006-01-02T15:04:05Z07:00"},
+		{"endpoint", "example.com", "expected url: invalid URL: missing scheme, e.g. https://example.com"},
+		{"listen", "localhost", "expected ip:port: invalid IP address and port, e.g. 127.0.0.1:8080 or [::1]:8080"},
+		{"bind", "300.0.0.1", "expected ip: invalid IP address, e.g. 127.0.0.1 or ::1"},
+		{"subnet", "10.0.0.0", "expected cidr: invalid CIDR prefix, e.g. 10.0.0.0/8"},
+		{"max-size", "12 parsecs", "expected size: invalid byte size, unknown unit \"parsecs\""},
+		{"match", "(", "expected regexp: invalid regular expression: missing closing )"},
+	}
+	for _, test := range tests {
+		args := []string{"serve", "--since", "2026-01-01", "--" + test.flag, test.value}
+		if code, _, errOut := execute(&cli, args...); code != 2 || !strings.Contains(errOut, test.err) {
+			t.Fatalf("%v: expected %q, got %q", args, test.err, errOut)
+		}
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		text string
+		size int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"512", 512, true},
+		{"1kB", 1000, true},
+		{"1kib", 1024, true},
+		{"1.5GiB", 3 << 29, true},
+		{"2 MB", 2000000, true},
+		{"0.5B", 0, false},
+		{"GB", 0, false},
+		{"-1MB", 0, false},
+		{"16EiB", 0, false},
+	}
+	for _, test := range tests {
+		size, err := parseByteSize(test.text)
+		if (err == nil) != test.ok || size != test.size {
+			t.Fatalf("%q: expected %d %t, got %d %v", test.text, test.size, test.ok, size, err)
+		}
+	}
+	for size, text := range map[int64]string{0: "0B", 1536: "1536B", 1 << 20: "1MiB", 3 << 29: "1536MiB", 2000: "2kB"} {
+		if s := formatByteSize(size); s != text {
+			t.Fatalf("%d: expected %q, got %q", size, text, s)
+		}
+	}
+}
+
+func TestRichTypesSpec(t *testing.T) {
+	cli := sampleTypesCli(t)
+	_, out, _ := execute(&cli, "help", "serve")
+	if !strings.Contains(out, "usage: app serve --since <time> [OPTIONS]") {
+		t.Fatalf("the usage should have the type name, got %q", out)
+	}
+	spec := cli.Spec()
+	labels := spec.Subcommands[0].Labels
+	if labels[1].Default != "30s" || labels[2].Default != "" || strings.Join(labels[0].Layouts, ",") != "2006-01-02,"+time.RFC3339 {
+		t.Fatalf("unexpected labels %+v", labels)
+	}
+	var b bytes.Buffer
+	writeJSON(&b, spec)
+	loaded, err := NewCliFromJSON(b.Bytes(), Handlers{"serve": func(h *SubcommandHandler) {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.Spec(), spec) {
+		t.Fatalf("the loaded CLI differs from the original:\n%+v\n%+v", loaded.Spec(), spec)
+	}
+}
+
+func TestRichTypeDefaultsSurviveParsing(t *testing.T) {
+	deflt := time.Date(2026, 3, 4, 10, 30, 0, 0, time.FixedZone("", 2*60*60))
+	cli := NewCli("1.0", "A CLI with a time default.")
+	sub, _ := NewSubcommandHandler("report", "Print a report.")
+	sub.AddTimeParamWithDefault([]string{"since"}, "when to start", deflt, "2006-01-02")
+	var got time.Time
+	sub.Handle(func(h *SubcommandHandler) {
+		got, _ = h.GetTime("since")
+	})
+	cli.HandleSubcommand(sub)
+	for k := 0; k < 2; k++ {
+		if code, _, errOut := execute(&cli, "report"); code != 0 || !got.Equal(deflt) || got.Format(time.RFC3339) != "2026-03-04T10:30:00+02:00" {
+			t.Fatalf("expected %s, got %d %s %q", deflt, code, got, errOut)
+		}
+	}
+	if _, _, _ = execute(&cli, "report", "--since", "2026-01-01"); got.Format(time.RFC3339) != "2026-01-01T00:00:00Z" {
+		t.Fatalf("unexpected time %s", got)
+	}
+	if execute(&cli, "report"); !got.Equal(deflt) {
+		t.Fatalf("the default should be restored, got %s", got)
+	}
+
+	// the default of a loaded value is set after the value is made
+	var endpoint string
+	loaded, err := NewCliFromJSON([]byte(`{"documentation": "A CLI.", "subcommands": [{"name": "report",
+		"documentation": "Print a report.", "labels": [{"name": "endpoint", "type": "url", "default": "https://example.com",
+		"documentation": "where to report"}]}]}`), Handlers{"report": func(h *SubcommandHandler) {
+		u, _ := h.GetURL("endpoint")
+		endpoint = fmt.Sprint(u)
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if execute(&loaded, "report"); endpoint != "https://example.com" {
+		t.Fatalf("expected the default URL, got %q", endpoint)
+	}
+}
+
+func TestRichTypesHelp(t *testing.T) {
+	cli := sampleTypesCli(t)
+	level := levelValue("info")
+	cli.subcommands[0].AddValueParam([]string{"level"}, "the log level", &level)
+	_, out, _ := execute(&cli, "help", "serve")
+	for _, want := range []string{
+		" --since <time>\t",
+		" --timeout <duration>\t",
+		" --listen <ip:port>\t",
+		" --max-size <size>\t",
+		" --match <regexp>\t",
+		" --level <level>\t",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package goldcmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Check the definition of the whole CLI and report every problem found at
+// once, as an ErrorList. The problems checked for are:
+//   - subcommand names that are reserved, duplicated, or not valid,
+//   - label aliases that are not valid,
+//   - aliases used by more than one argument, parameter or positional argument,
+//   - missing documentation for the CLI, a subcommand, or a label,
+//   - examples that use flags the subcommand does not know about,
+//   - labels named "show-config", or "config" when the CLI reads
+//     configuration files, which are used by global flags.
+//
+// Validate is run before every execution if debug mode is on, see SetDebug.
+func (cli *Cli) Validate() error {
+	errs := make(ErrorList, 0)
+	if strings.TrimSpace(cli.documentation) == "" {
+		errs = append(errs, fmt.Errorf("the CLI has no documentation"))
+	}
+	reserved := map[string]bool{"help": true, "__complete": true}
+	for _, builtin := range cli.builtinSubcommands() {
+		reserved[builtin.name] = true
+	}
+	for _, subcmd := range cli.subcommands {
+		if reserved[subcmd.name] {
+			errs = append(errs, fmt.Errorf("subcommand \"%s\": the name is reserved", subcmd.name))
+		}
+	}
+	errs = append(errs, validateSubcommands(cli.subcommands, nil)...)
+	globals := []string{"show-config"}
+	if cli.configFiles != nil {
+		globals = append(globals, "config")
+	}
+	byKey := cli.subcommandsByKey()
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, global := range globals {
+			if parserForAlias(global, byKey[key].parsers()) != nil {
+				errs = append(errs, fmt.Errorf("subcommand \"%s\": the alias \"%s\" is used by the global --%s flag", strings.ReplaceAll(key, ".", " "), global, global))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Check a list of sibling subcommands and their descendants, where `path`
+// holds the command words leading to the siblings.
+func validateSubcommands(subcmds []*SubcommandHandler, path []string) ErrorList {
+	errs := make(ErrorList, 0)
+	seen := make(map[string]bool)
+	for _, subcmd := range subcmds {
+		where := strings.Join(append(append([]string{}, path...), subcmd.name), " ")
+		if seen[subcmd.name] {
+			errs = append(errs, fmt.Errorf("subcommand \"%s\": the name is used by another subcommand", where))
+		}
+		seen[subcmd.name] = true
+		errs = append(errs, subcmd.validate(where)...)
+		errs = append(errs, validateSubcommands(subcmd.subcommands, append(append([]string{}, path...), subcmd.name))...)
+	}
+	return errs
+}
+
+// Check the definition of a single subcommand, where `where` is the command
+// words naming it.
+func (h *SubcommandHandler) validate(where string) ErrorList {
+	errs := make(ErrorList, 0)
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf("subcommand \"%s\": %s", where, fmt.Sprintf(format, a...)))
+	}
+	if !aliasIsValid(h.name) {
+		fail("the name is not valid")
+	}
+	if strings.TrimSpace(h.documentation) == "" {
+		fail("the subcommand has no documentation")
+	}
+	// every alias must be valid and used by a single label
+	owners := make(map[string]string)
+	use := func(alias string, owner string) {
+		if !aliasIsValid(alias) {
+			fail("the %s alias \"%s\" is not valid", owner, alias)
+		}
+		if other, ok := owners[alias]; ok {
+			fail("the alias \"%s\" is used by both an %s and a %s", alias, other, owner)
+		}
+		owners[alias] = owner
+	}
+	for _, group := range []struct {
+		owner string
+		cp    *commandParser
+	}{{"argument", h.argparser}, {"parameter", h.paramparser}} {
+		for _, row := range group.cp.labelRows() {
+			for _, alias := range row {
+				use(alias, group.owner)
+			}
+			if strings.TrimSpace(group.cp.docOf(row[0])) == "" {
+				fail("the %s %s has no documentation", group.owner, group.cp.flagName(row[0]))
+			}
+		}
+	}
+	for _, p := range h.positionals {
+		use(p.name, "positional argument")
+		if strings.TrimSpace(p.documentation) == "" {
+			fail("the positional argument <%s> has no documentation", p.name)
+		}
+	}
+	// every flag in the examples must be known
+	for _, ex := range h.examples {
+		for _, token := range strings.Fields(ex.command) {
+			if token == "--" {
+				break
+			}
+			label := strings.TrimLeft(token, "-")
+			if i := strings.IndexByte(label, '='); i >= 0 {
+				label = label[:i]
+			}
+			if !strings.HasPrefix(token, "-") || label == "" || isNumber(token) || isHelpToken(token) {
+				continue
+			}
+			if h.clusterShortFlags && !strings.HasPrefix(token, "--") {
+				// e.g. '-xvf', which is read as '-x -v -f'
+				if flag := h.unknownClusterFlag(token); flag != "" {
+					fail("the example \"%s\" uses the unknown flag %s", ex.command, flag)
+				}
+				continue
+			}
+			if parserForAlias(label, h.parsers()) == nil {
+				fail("the example \"%s\" uses the unknown flag %s", ex.command, token)
+			}
+		}
+	}
+	return errs
+}
+
+// Get the first unknown flag of a cluster of short flags e.g. "-q" for
+// '-xq', or an empty string if they are all known. The flags after the
+// first one that takes a value are its value e.g. 'a.tar' in '-xfa.tar'.
+func (h *SubcommandHandler) unknownClusterFlag(token string) string {
+	chars := []rune(token[1:])
+	for i, c := range chars {
+		label := string(c)
+		cp := parserForAlias(label, h.parsers())
+		if cp == nil {
+			return "-" + label
+		}
+		if !cp.isBool(label) || (i+1 < len(chars) && chars[i+1] == '=') {
+			break
+		}
+	}
+	return ""
+}
+
+// Turn debug mode on or off. In debug mode, the CLI definition is checked
+// with Validate before every execution, and the execution fails if there is
+// a problem. Debug mode is also on if the GOLDCMD_DEBUG environment variable
+// is set, which is useful in tests and during development.
+func (cli *Cli) SetDebug(debug bool) {
+	cli.debug = debug
+}
+
+// Return true if the CLI definition should be checked before execution.
+func (cli *Cli) debugging() bool {
+	return cli.debug || os.Getenv("GOLDCMD_DEBUG") != ""
+}
+
+// Print the problems with the CLI definition to `w`, and return the exit
+// status for them.
+func printDefinitionErrors(w io.Writer, err error) int {
+	fmt.Fprintf(w, "error: the CLI definition is not valid\n")
+	for _, e := range err.(ErrorList) {
+		fmt.Fprintf(w, "  %s\n", e)
+	}
+	// EX_SOFTWARE from sysexits.h
+	return 70
+}
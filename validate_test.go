@@ -0,0 +1,91 @@
+package goldcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAliasIsValid(t *testing.T) {
+	for _, name := range []string{"a", "apple", "dry-run", "max_count", "v2"} {
+		if !aliasIsValid(name) {
+			t.Fatalf("alias %q should be valid", name)
+		}
+	}
+	for _, name := range []string{"", "-a", "2a", "a b", "a=b", "a.b"} {
+		if aliasIsValid(name) {
+			t.Fatalf("alias %q should not be valid", name)
+		}
+	}
+}
+
+func TestValidateSampleCli(t *testing.T) {
+	cli := sampleCli(t)
+	if err := cli.Validate(); err != nil {
+		t.Fatalf("the sample CLI should be valid: %v", err)
+	}
+}
+
+func TestLabelsWithTheSameDocumentation(t *testing.T) {
+	cli := NewCli("1.0", "A CLI.")
+	sub, _ := NewSubcommandHandler("sum", "Add numbers.")
+	sub.AddIntParamWithDefault([]string{"a"}, "a number", 0)
+	sub.AddIntParamWithDefault([]string{"b"}, "a number", 0)
+	sub.Handle(func(h *SubcommandHandler) {})
+	cli.HandleSubcommand(sub)
+	if err := cli.Validate(); err != nil {
+		t.Fatalf("labels may share documentation: %v", err)
+	}
+	_, out, _ := execute(&cli, "help", "sum")
+	if !strings.Contains(out, " --a <int>\ta number\n --b <int>\ta number\n") {
+		t.Fatalf("both labels should be in the help, got %q", out)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cli := NewCli("1.0", "")
+	help, _ := NewSubcommandHandler("help", "My own help.")
+	cli.HandleSubcommand(help)
+	a, _ := NewSubcommandHandler("a", "First a.")
+	a.AddIntArg([]string{"count"}, "")
+	// bypass the checks of the public API
+	a.paramparser.addIntArg([]string{"count", "bad alias"}, "a count")
+	a.Example("", "app a --count 1 --cuont 2", "")
+	cli.HandleSubcommand(a)
+	again, _ := NewSubcommandHandler("a", "Second a.")
+	cli.HandleSubcommand(again)
+
+	err := cli.Validate()
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected an ErrorList, got %v", err)
+	}
+	for _, want := range []string{
+		"the CLI has no documentation",
+		"subcommand \"help\": the name is reserved",
+		"subcommand \"a\": the argument --count has no documentation",
+		"subcommand \"a\": the alias \"count\" is used by both an argument and a parameter",
+		"subcommand \"a\": the parameter alias \"bad alias\" is not valid",
+		"subcommand \"a\": the example \"app a --count 1 --cuont 2\" uses the unknown flag --cuont",
+		"subcommand \"a\": the name is used by another subcommand",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected the problem %q, got:\n%s", want, err)
+		}
+	}
+	if len(list) != 7 {
+		t.Fatalf("expected 7 problems, got:\n%s", err)
+	}
+}
+
+func TestDebugModeValidates(t *testing.T) {
+	cli := sampleCli(t)
+	cli.subcommands[0].documentation = ""
+	if code, _, _ := execute(&cli, "greet", "--name", "x"); code != 0 {
+		t.Fatalf("the CLI should only be validated in debug mode")
+	}
+	cli.SetDebug(true)
+	code, out, errOut := execute(&cli, "greet", "--name", "x")
+	if code != 70 || out != "" || !strings.Contains(errOut, "subcommand \"greet\": the subcommand has no documentation") {
+		t.Fatalf("an invalid CLI should not run in debug mode, got %d %q", code, errOut)
+	}
+}
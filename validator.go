@@ -0,0 +1,314 @@
+package goldcmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// A Validator is a rule the value of an argument, parameter or positional
+// argument must follow, which is checked once the command line is parsed.
+// The values of list labels and variadic positional arguments are checked
+// one by one.
+type Validator struct {
+	// the rule as shown in help e.g. "even", or the comparison of a numeric
+	// rule e.g. ">=", which is followed by its bound
+	description string
+	// the name of the rule in the spec e.g. "at_least", see ValidatorSpec
+	rule string
+	// the bound of a numeric rule, or the length of a length rule
+	bound float64
+	// the regular expression of a "matches" rule
+	pattern string
+	// the kind of value the rule applies to, "number" or "string", or an
+	// empty string for any value
+	kind string
+	// check a value, returning an error saying what is wrong with it, where
+	// `format` shows a number as a value of the label e.g. "1MiB"
+	check func(value interface{}, format func(n float64) string) error
+}
+
+// Get a number as it is shown in help and errors.
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// Get the function that shows a number as a value of a label, so the bound
+// of a rule on a byte size or a duration reads e.g. "1MiB" or "1s".
+func numberFormat(value Value) func(n float64) string {
+	switch value.(type) {
+	case *byteSizeValue:
+		return func(n float64) string { return formatByteSize(int64(n)) }
+	case *durationValue:
+		return func(n float64) string { return time.Duration(n).String() }
+	}
+	return formatNumber
+}
+
+// Get the rule as shown in help e.g. ">= 1".
+func (v Validator) describe(format func(n float64) string) string {
+	if v.kind == "number" {
+		return v.description + " " + format(v.bound)
+	}
+	return v.description
+}
+
+// Get the number a value holds, where a byte size is a number of bytes and a
+// duration a number of nanoseconds. The second return value is false if the
+// value is not a number.
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case time.Duration:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Get a Validator for a numeric value, where `ok` is true if the number
+// follows the rule and `reason` is the rule as shown in errors e.g. "at
+// least".
+func numberValidator(rule string, description string, reason string, bound float64, ok func(n float64) bool) Validator {
+	return Validator{rule: rule, description: description, bound: bound, kind: "number", check: func(value interface{}, format func(n float64) string) error {
+		if n, _ := toNumber(value); !ok(n) {
+			return fmt.Errorf("must be %s %s", reason, format(bound))
+		}
+		return nil
+	}}
+}
+
+// Get a Validator for a number that must be at least `min`. A byte size is
+// compared in bytes and a duration in nanoseconds, e.g.
+// AtLeast(float64(time.Second)).
+func AtLeast(min float64) Validator {
+	return numberValidator("at_least", ">=", "at least", min, func(n float64) bool { return n >= min })
+}
+
+// Get a Validator for a number that must be at most `max`.
+func AtMost(max float64) Validator {
+	return numberValidator("at_most", "<=", "at most", max, func(n float64) bool { return n <= max })
+}
+
+// Get a Validator for a number that must be greater than `min`.
+func GreaterThan(min float64) Validator {
+	return numberValidator("greater_than", ">", "greater than", min, func(n float64) bool { return n > min })
+}
+
+// Get a Validator for a number that must be less than `max`.
+func LessThan(max float64) Validator {
+	return numberValidator("less_than", "<", "less than", max, func(n float64) bool { return n < max })
+}
+
+// Get a Validator for a string that must be at least `min` characters long.
+func MinLength(min int) Validator {
+	return Validator{rule: "min_length", description: fmt.Sprintf("length >= %d", min), bound: float64(min), kind: "string", check: func(value interface{}, _ func(n float64) string) error {
+		if utf8.RuneCountInString(value.(string)) < min {
+			return fmt.Errorf("must be at least %d characters long", min)
+		}
+		return nil
+	}}
+}
+
+// Get a Validator for a string that must be at most `max` characters long.
+func MaxLength(max int) Validator {
+	return Validator{rule: "max_length", description: fmt.Sprintf("length <= %d", max), bound: float64(max), kind: "string", check: func(value interface{}, _ func(n float64) string) error {
+		if utf8.RuneCountInString(value.(string)) > max {
+			return fmt.Errorf("must be at most %d characters long", max)
+		}
+		return nil
+	}}
+}
+
+// Get a Validator for a string that must match a regular expression. Use
+// anchors to match the whole string e.g. "^[a-z]+$".
+func Matches(re *regexp.Regexp) Validator {
+	return Validator{rule: "matches", description: "matches " + re.String(), pattern: re.String(), kind: "string", check: func(value interface{}, _ func(n float64) string) error {
+		if !re.MatchString(value.(string)) {
+			return fmt.Errorf("must match %s", re)
+		}
+		return nil
+	}}
+}
+
+// Get a Validator from a function, which returns an error saying what is
+// wrong with a value, and a description of the rule for help e.g. "even".
+// The function is given the underlying Go value e.g. an int for an integer
+// label, an int64 for a byte size or a time.Duration for a duration, or the
+// Value itself for a value without one.
+func Check(description string, f func(value interface{}) error) Validator {
+	if f == nil {
+		return Validator{rule: "check", description: description}
+	}
+	return Validator{rule: "check", description: description, check: func(value interface{}, _ func(n float64) string) error {
+		return f(value)
+	}}
+}
+
+// Get the kind of a value for validators, "number" or "string", or an empty
+// string for any other value.
+func validatorKind(value interface{}) string {
+	switch value.(type) {
+	case int, int64, float64, time.Duration, []int, []float64:
+		return "number"
+	case string, []string:
+		return "string"
+	}
+	return ""
+}
+
+// Get the items of a value to check one by one: each item of a list, or the
+// value itself.
+func validatorItems(value interface{}) []interface{} {
+	items := make([]interface{}, 0)
+	switch v := value.(type) {
+	case []int:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []float64:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	default:
+		items = append(items, value)
+	}
+	return items
+}
+
+// Get the underlying Go value of a Value for validators, or the Value itself
+// if it does not have one. Byte sizes and durations are numbers.
+func validatorValue(value Value) interface{} {
+	switch v := value.(type) {
+	case *byteSizeValue:
+		return int64(*v)
+	case *durationValue:
+		return time.Duration(*v)
+	case getter:
+		return v.get()
+	}
+	return value
+}
+
+// Get the validators of the label an alias belongs to, or of a positional
+// argument, and the function that shows their bounds.
+func (cp *commandParser) validatorsOf(alias string) ([]Validator, func(n float64) string) {
+	name := alias
+	if row := cp.aliasesOf(alias); len(row) > 0 {
+		name = row[0]
+	}
+	format := formatNumber
+	if l := cp.labelOf(name); l != nil {
+		format = numberFormat(l.value)
+	}
+	return cp.validators[name], format
+}
+
+// Get the descriptions of the validators of the label an alias belongs to,
+// or of a positional argument, for help.
+func (cp *commandParser) rules(alias string) []string {
+	validators, format := cp.validatorsOf(alias)
+	rules := make([]string, 0)
+	for _, v := range validators {
+		rules = append(rules, v.describe(format))
+	}
+	return rules
+}
+
+// Get the specs of the validators of the label an alias belongs to, or of a
+// positional argument, or nil if there are none.
+func (cp *commandParser) validatorSpecs(alias string) []ValidatorSpec {
+	validators, format := cp.validatorsOf(alias)
+	var specs []ValidatorSpec
+	for _, v := range validators {
+		specs = append(specs, ValidatorSpec{Rule: v.rule, Value: v.bound, Pattern: v.pattern, Description: v.describe(format)})
+	}
+	return specs
+}
+
+// Get an error for every item of a value that does not follow one of the
+// validators, where `name` is how the label is shown e.g. "--count" and
+// `format` shows a number as a value of the label.
+func checkValidators(name string, value interface{}, format func(n float64) string, validators []Validator) []error {
+	errs := make([]error, 0)
+	for _, item := range validatorItems(value) {
+		shown := fmt.Sprint(item)
+		if n, ok := toNumber(item); ok {
+			shown = format(n)
+		}
+		for _, v := range validators {
+			if err := v.check(item, format); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value \"%s\" for %s: %s", shown, name, err))
+			}
+		}
+	}
+	return errs
+}
+
+// Get an error for every value of the subcommand that does not follow one of
+// its validators. Values that are not set are not checked.
+func (h *SubcommandHandler) checkValidators() []error {
+	errs := make([]error, 0)
+	for _, p := range h.positionals {
+		if p.variadic {
+			errs = append(errs, checkValidators(p.usageToken(), h.variadicValues[p.name], formatNumber, h.posparser.validators[p.name])...)
+		} else if h.posparser.isSet(p.name) {
+			l := h.posparser.labelOf(p.name)
+			errs = append(errs, checkValidators(p.usageToken(), validatorValue(l.value), numberFormat(l.value), h.posparser.validators[p.name])...)
+		}
+	}
+	for _, cp := range []*commandParser{h.argparser, h.paramparser} {
+		for _, row := range cp.labelRows() {
+			if cp.isSet(row[0]) {
+				l := cp.labelOf(row[0])
+				errs = append(errs, checkValidators(cp.flagName(row[0]), validatorValue(l.value), numberFormat(l.value), cp.validators[row[0]])...)
+			}
+		}
+	}
+	return errs
+}
+
+// Add validators to an argument, parameter or positional argument, where
+// `label` is any of its aliases or its name. The values that do not follow
+// them are reported together, and the subcommand fails without running its
+// handler function. The rules are shown in help.
+// An error is returned if a validator does not apply to the type of the
+// value, e.g. AtLeast for a string.
+func (h *SubcommandHandler) AddValidators(label string, validators ...Validator) error {
+	var cp *commandParser
+	var name string
+	var value interface{}
+	if p := h.positional(label); p != nil {
+		cp, name = h.posparser, p.name
+		if p.variadic {
+			value = []string{}
+		} else {
+			value = validatorValue(h.posparser.labelOf(p.name).value)
+		}
+	} else if cp = parserForAlias(label, h.parsers()); cp != nil {
+		name = cp.aliasesOf(label)[0]
+		value = validatorValue(cp.labelOf(label).value)
+	} else {
+		return fmt.Errorf("unknown label \"%s\"", label)
+	}
+	for _, v := range validators {
+		if v.check == nil {
+			return fmt.Errorf("invalid validator for \"%s\"", label)
+		}
+		if v.kind != "" && v.kind != validatorKind(value) {
+			return fmt.Errorf("the validator %q does not apply to the value of \"%s\"", v.describe(formatNumber), label)
+		}
+	}
+	cp.validators[name] = append(cp.validators[name], validators...)
+	return nil
+}
@@ -0,0 +1,181 @@
+package goldcmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleValidatorCli(t *testing.T) Cli {
+	cli := NewCli("1.0", "A CLI with validators.")
+	sub, _ := NewSubcommandHandler("create", "Create a user.")
+	sub.AddStrPositional("name", "the name of the user")
+	sub.AddVariadicPositional("groups", "the groups to join", 0, -1)
+	sub.AddIntParamWithDefault([]string{"age"}, "the age of the user", 30)
+	sub.AddFloatParamWithDefault([]string{"quota"}, "the share of the disk", 0.5)
+	sub.AddIntsParamWithDefault([]string{"port"}, "the ports to open", []int{})
+	even := Check("even", func(value interface{}) error {
+		if value.(int)%2 != 0 {
+			return errors.New("must be even")
+		}
+		return nil
+	})
+	for _, err := range []error{
+		sub.AddValidators("name", MinLength(2), MaxLength(8), Matches(regexp.MustCompile("^[a-z]+$"))),
+		sub.AddValidators("groups", Matches(regexp.MustCompile("^[a-z]+$"))),
+		sub.AddValidators("age", AtLeast(18), LessThan(150)),
+		sub.AddValidators("quota", GreaterThan(0), AtMost(1)),
+		sub.AddValidators("port", AtLeast(1), even),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sub.Handle(func(h *SubcommandHandler) {
+		name, _ := h.GetStr("name")
+		age, _ := h.GetInt("age")
+		fmt.Fprintf(h.Stdout(), "%s %d", name, age)
+	})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func TestValidators(t *testing.T) {
+	cli := sampleValidatorCli(t)
+	code, out, errOut := execute(&cli, "create", "alice", "admins", "--age", "18", "--quota", "1", "--port", "8")
+	if want := "alice 18"; code != 0 || out != want {
+		t.Fatalf("expected %q, got %d %q %q", want, code, out, errOut)
+	}
+	code, _, errOut = execute(&cli, "create", "Al1ce", "Admins", "--age", "150", "--quota", "0", "--port", "0", "--port", "3")
+	for _, want := range []string{
+		`error: invalid value "Al1ce" for <name>: must match ^[a-z]+$`,
+		`error: invalid value "Admins" for [groups...]: must match ^[a-z]+$`,
+		`error: invalid value "150" for --age: must be less than 150`,
+		`error: invalid value "0" for --quota: must be greater than 0`,
+		`error: invalid value "0" for --port: must be at least 1`,
+		`error: invalid value "3" for --port: must be even`,
+	} {
+		if code != 2 || !strings.Contains(errOut, want) {
+			t.Fatalf("expected %q, got %d %q", want, code, errOut)
+		}
+	}
+	if code, _, errOut = execute(&cli, "create", "abcdefghi"); code != 2 || !strings.Contains(errOut, "must be at most 8 characters long") {
+		t.Fatalf("expected a length error, got %d %q", code, errOut)
+	}
+}
+
+func TestValidatorErrors(t *testing.T) {
+	sub, _ := NewSubcommandHandler("create", "Create a user.")
+	sub.AddStrArg([]string{"name"}, "the name of the user")
+	sub.AddIntArg([]string{"age"}, "the age of the user")
+	if err := sub.AddValidators("name", AtLeast(1)); err == nil {
+		t.Fatalf("a numeric validator should not apply to a string")
+	}
+	if err := sub.AddValidators("age", MinLength(1)); err == nil {
+		t.Fatalf("a string validator should not apply to an integer")
+	}
+	if err := sub.AddValidators("missing", AtLeast(1)); err == nil {
+		t.Fatalf("an unknown label should be an error")
+	}
+	if err := sub.AddValidators("age", Validator{}); err == nil {
+		t.Fatalf("an empty validator should be an error")
+	}
+}
+
+func TestValidatorHelp(t *testing.T) {
+	cli := sampleValidatorCli(t)
+	_, out, _ := execute(&cli, "help", "create")
+	for _, want := range []string{
+		" <name>\tthe name of the user (length >= 2, length <= 8, matches ^[a-z]+$)\n",
+		" --age <int>\tthe age of the user [>= 18, < 150]\n",
+		" --quota <float>\tthe share of the disk [> 0, <= 1]\n",
+		" --port <int>\tthe ports to open [repeatable] [>= 1, even]\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+}
+
+func TestValidatorsOnByteSizesAndDurations(t *testing.T) {
+	cli := NewCli("1.0", "A CLI with validators.")
+	sub, _ := NewSubcommandHandler("upload", "Upload a file.")
+	sub.AddByteSizeParamWithDefault([]string{"max-size"}, "the largest file to upload", 1024)
+	sub.AddDurationParamWithDefault([]string{"timeout"}, "how long to wait", time.Minute)
+	for _, err := range []error{
+		sub.AddValidators("max-size", AtMost(1<<20)),
+		sub.AddValidators("timeout", AtLeast(float64(time.Second))),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sub.Handle(func(h *SubcommandHandler) {})
+	cli.HandleSubcommand(sub)
+	_, out, _ := execute(&cli, "help", "upload")
+	for _, want := range []string{" [<= 1MiB]\n", " [>= 1s]\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in the help, got %q", want, out)
+		}
+	}
+	if code, _, errOut := execute(&cli, "upload", "--max-size", "1MiB", "--timeout", "1s"); code != 0 {
+		t.Fatalf("unexpected error %q", errOut)
+	}
+	code, _, errOut := execute(&cli, "upload", "--max-size", "2MiB", "--timeout", "10ms")
+	for _, want := range []string{
+		`error: invalid value "2MiB" for --max-size: must be at most 1MiB`,
+		`error: invalid value "10ms" for --timeout: must be at least 1s`,
+	} {
+		if code != 2 || !strings.Contains(errOut, want) {
+			t.Fatalf("expected %q, got %d %q", want, code, errOut)
+		}
+	}
+}
+
+func TestValidatorSpec(t *testing.T) {
+	cli := sampleValidatorCli(t)
+	spec := cli.Spec()
+	create := spec.Subcommands[0]
+	want := []ValidatorSpec{{Rule: "at_least", Value: 1, Description: ">= 1"}, {Rule: "check", Description: "even"}}
+	if !reflect.DeepEqual(create.Labels[2].Validators, want) {
+		t.Fatalf("expected %+v, got %+v", want, create.Labels[2].Validators)
+	}
+	want = []ValidatorSpec{{Rule: "min_length", Value: 2, Description: "length >= 2"}, {Rule: "max_length", Value: 8, Description: "length <= 8"},
+		{Rule: "matches", Pattern: "^[a-z]+$", Description: "matches ^[a-z]+$"}}
+	if !reflect.DeepEqual(create.Positionals[0].Validators, want) {
+		t.Fatalf("expected %+v, got %+v", want, create.Positionals[0].Validators)
+	}
+	var b bytes.Buffer
+	writeJSON(&b, spec)
+	_, err := NewCliFromJSON(b.Bytes(), Handlers{"create": func(h *SubcommandHandler) {}})
+	if err == nil || !strings.Contains(err.Error(), "subcommands[0].labels[2].validators[1].rule: a rule made with Check cannot be loaded") {
+		t.Fatalf("expected an error for the custom rule, got %v", err)
+	}
+
+	// without the custom rule, the spec round trips
+	create.Labels[2].Validators = create.Labels[2].Validators[:1]
+	b.Reset()
+	writeJSON(&b, spec)
+	loaded, err := NewCliFromJSON(b.Bytes(), Handlers{"create": func(h *SubcommandHandler) {}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.Spec(), spec) {
+		t.Fatalf("the loaded CLI differs from the original:\n%+v\n%+v", loaded.Spec(), spec)
+	}
+	if code, _, errOut := execute(&loaded, "create", "Al1ce", "--age", "150"); code != 2 || !strings.Contains(errOut, "must be less than 150") || !strings.Contains(errOut, "must match ^[a-z]+$") {
+		t.Fatalf("the loaded validators should be checked, got %d %q", code, errOut)
+	}
+	create.Labels[2].Validators[0].Rule = "between"
+	b.Reset()
+	writeJSON(&b, spec)
+	_, err = NewCliFromJSON(b.Bytes(), Handlers{"create": func(h *SubcommandHandler) {}})
+	if err == nil || !strings.Contains(err.Error(), `subcommands[0].labels[2].validators[0].rule: unknown rule "between"`) {
+		t.Fatalf("expected an error for an unknown rule, got %v", err)
+	}
+}
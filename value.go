@@ -0,0 +1,285 @@
+package goldcmd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// The value of a label or positional argument, which is set from the text
+// given on the command line. It is compatible with flag.Value from the
+// standard library, with a type name for help, so the same values can be
+// used with both packages.
+//
+// A value can also have these methods, which are used if they exist:
+//   - IsBoolFlag() bool, like a flag.Value, which returns true if the label
+//     can be given without a value e.g. '--verbose', which sets it to "true",
+//   - Reset(), which restores the default, so a subcommand can be run more
+//     than once. Values without it are restored by setting them to the
+//     text their String method gave when they were added, which is enough
+//     for values that keep only the last value they are set to.
+type Value interface {
+	// Set the value from its text, or return an error if the text is not
+	// valid.
+	Set(s string) error
+	// Get the value formatted as it would be given on the command line.
+	String() string
+	// Get the type name of the value for help e.g. "int".
+	Type() string
+}
+
+// A value that can be given without text, like a flag.Value.
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// A value that can restore its own default.
+type resetter interface {
+	Reset()
+}
+
+// A value that restores its own default, and keeps its current value as
+// the default when it is added as a parameter.
+type defaultKeeper interface {
+	resetter
+	keepDefault()
+}
+
+// A value with an underlying Go value, which the getters return.
+type getter interface {
+	get() interface{}
+}
+
+// A value holding a list, which is set from several values at once.
+type listValue interface {
+	Value
+	// Set the values from their text, adding them to the current values if
+	// `add` is set or replacing them otherwise. Nothing is changed if one of
+	// the values is not valid.
+	setItems(items []string, add bool) error
+	// Get the values formatted as they would be given on the command line.
+	items() []string
+}
+
+// Return true if a value can be given without text.
+func isBoolValue(v Value) bool {
+	b, ok := v.(boolFlag)
+	return ok && b.IsBoolFlag()
+}
+
+// The value of an integer label.
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string   { return strconv.Itoa(int(*v)) }
+func (v *intValue) Type() string     { return "int" }
+func (v *intValue) get() interface{} { return int(*v) }
+
+// The value of a string label.
+type strValue string
+
+func (v *strValue) Set(s string) error {
+	*v = strValue(s)
+	return nil
+}
+
+func (v *strValue) String() string   { return string(*v) }
+func (v *strValue) Type() string     { return "str" }
+func (v *strValue) get() interface{} { return string(*v) }
+
+// The value of a float label.
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string   { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *floatValue) Type() string     { return "float" }
+func (v *floatValue) get() interface{} { return float64(*v) }
+
+// The value of a Boolean label. Any text other than "false" sets it, so
+// '--verbose', '--verbose=true' and '--verbose=yes' are the same. Values from
+// the environment and configuration files are stricter, see explicitBool.
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	*v = boolValue(s != "false")
+	return nil
+}
+
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) Type() string     { return "bool" }
+func (v *boolValue) IsBoolFlag() bool { return true }
+func (v *boolValue) get() interface{} { return bool(*v) }
+
+// Get the text to set a Boolean value with from the environment or a
+// configuration file, where it must be one strconv.ParseBool accepts e.g.
+// "true", "0" or "F", so 'APP_DRY=no' is an error rather than true. Other
+// values are returned as they are.
+func explicitBool(v Value, s string) (string, error) {
+	if !isBoolValue(v) {
+		return s, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatBool(b), nil
+}
+
+// The value of a string list label.
+type strsValue struct {
+	values []string
+}
+
+func (v *strsValue) Set(s string) error {
+	return v.setItems([]string{s}, true)
+}
+
+func (v *strsValue) setItems(items []string, add bool) error {
+	// a new slice is made every time, so the slices the handler got from
+	// GetStrs do not change
+	values := make([]string, 0)
+	if add {
+		values = append(values, v.values...)
+	}
+	v.values = append(values, items...)
+	return nil
+}
+
+func (v *strsValue) items() []string  { return v.values }
+func (v *strsValue) String() string   { return strings.Join(v.values, ",") }
+func (v *strsValue) Type() string     { return "[]str" }
+func (v *strsValue) get() interface{} { return v.values }
+
+// The value of an integer list label.
+type intsValue struct {
+	values []int
+}
+
+func (v *intsValue) Set(s string) error {
+	return v.setItems([]string{s}, true)
+}
+
+func (v *intsValue) setItems(items []string, add bool) error {
+	values := make([]int, 0)
+	if add {
+		values = append(values, v.values...)
+	}
+	for _, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return err
+		}
+		values = append(values, n)
+	}
+	v.values = values
+	return nil
+}
+
+func (v *intsValue) items() []string  { return intStrings(v.values) }
+func (v *intsValue) String() string   { return strings.Join(v.items(), ",") }
+func (v *intsValue) Type() string     { return "[]int" }
+func (v *intsValue) get() interface{} { return v.values }
+
+// The value of a float list label.
+type floatsValue struct {
+	values []float64
+}
+
+func (v *floatsValue) Set(s string) error {
+	return v.setItems([]string{s}, true)
+}
+
+func (v *floatsValue) setItems(items []string, add bool) error {
+	values := make([]float64, 0)
+	if add {
+		values = append(values, v.values...)
+	}
+	for _, item := range items {
+		f, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return err
+		}
+		values = append(values, f)
+	}
+	v.values = values
+	return nil
+}
+
+func (v *floatsValue) items() []string  { return floatStrings(v.values) }
+func (v *floatsValue) String() string   { return strings.Join(v.items(), ",") }
+func (v *floatsValue) Type() string     { return "[]float" }
+func (v *floatsValue) get() interface{} { return v.values }
+
+// Add a required argument with a value of any type to the subcommand, e.g.
+// a type defined by the application. The value is set from the text given
+// on the command line, and read with GetValue.
+// If one of the aliases is used by another argument or
+// parameter, the function will return an error and handler will not be mutated.
+func (h *SubcommandHandler) AddValueArg(aliases []string, doc string, value Value) error {
+	if value == nil || !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.argparser.addLabel(aliases, doc, value)
+	return nil
+}
+
+// Add a parameter with a value of any type to the subcommand. The current
+// value is the default.
+func (h *SubcommandHandler) AddValueParam(aliases []string, doc string, value Value) error {
+	if value == nil || !h.checkAliasesAllowed(aliases) {
+		return errors.New("invalid label value")
+	}
+	h.paramparser.addLabelWithDefault(aliases, doc, value)
+	return nil
+}
+
+// Add a required positional argument with a value of any type to the
+// subcommand. The value is read with GetValue.
+func (h *SubcommandHandler) AddValuePositional(name string, doc string, value Value) error {
+	if value == nil {
+		return errors.New("invalid label value")
+	}
+	return h.addPositional(&positional{name: name, documentation: doc}, value)
+}
+
+// Get the value of an argument, parameter or positional argument, e.g. to
+// read a value added with AddValueArg.
+// Warning: This function will fail if the command line arguments have
+// not already been parsed.
+func (h *SubcommandHandler) GetValue(key string) (Value, error) {
+	for _, cp := range []*commandParser{h.argparser, h.paramparser, h.posparser} {
+		if l := cp.labelOf(key); l != nil && cp.isSet(key) {
+			return l.value, nil
+		}
+	}
+	return nil, errors.New("key not available")
+}
+
+// Get the underlying Go value of a built-in argument, parameter or
+// positional argument, or nil if there is no such value.
+func (h *SubcommandHandler) get(key string) interface{} {
+	value, err := h.GetValue(key)
+	if err != nil {
+		return nil
+	}
+	if g, ok := value.(getter); ok {
+		return g.get()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package goldcmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// A log level, as an application would define it.
+type levelValue string
+
+func (v *levelValue) Set(s string) error {
+	switch s {
+	case "debug", "info", "error":
+		*v = levelValue(s)
+		return nil
+	}
+	return fmt.Errorf("unknown level")
+}
+
+func (v *levelValue) String() string { return string(*v) }
+func (v *levelValue) Type() string   { return "level" }
+
+// A list of key=value pairs, which keeps every value it is given.
+type pairsValue struct {
+	pairs []string
+}
+
+func (v *pairsValue) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("expected key=value")
+	}
+	v.pairs = append(v.pairs, s)
+	return nil
+}
+
+func (v *pairsValue) String() string { return strings.Join(v.pairs, " ") }
+func (v *pairsValue) Type() string   { return "pair" }
+func (v *pairsValue) Reset()         { v.pairs = nil }
+
+// A switch, which can be given without a value.
+type switchValue struct {
+	on bool
+}
+
+func (v *switchValue) Set(s string) error {
+	v.on = s == "true"
+	return nil
+}
+
+func (v *switchValue) String() string   { return fmt.Sprint(v.on) }
+func (v *switchValue) Type() string     { return "switch" }
+func (v *switchValue) IsBoolFlag() bool { return true }
+
+var _ flag.Value = new(levelValue)
+var _ Value = new(intValue)
+
+func sampleValueCli(t *testing.T) Cli {
+	cli := NewCli("1.0", "A CLI with custom values.")
+	sub, _ := NewSubcommandHandler("run", "Run something.")
+	level := levelValue("info")
+	pairs := &pairsValue{}
+	for _, err := range []error{
+		sub.AddValueParam([]string{"level", "l"}, "the log level", &level),
+		sub.AddValueArg([]string{"set"}, "a key=value pair", pairs),
+		sub.AddValueParam([]string{"fast"}, "go fast", &switchValue{}),
+		sub.AddValuePositional("target", "what to run", new(levelValue)),
+	} {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sub.Handle(func(h *SubcommandHandler) {
+		l, _ := h.GetValue("l")
+		set, _ := h.GetValue("set")
+		fast, _ := h.GetValue("fast")
+		target, _ := h.GetValue("target")
+		fmt.Fprintf(h.Stdout(), "%s [%s] %s %s", l, set, fast, target)
+	})
+	cli.HandleSubcommand(sub)
+	return cli
+}
+
+func TestValueFlags(t *testing.T) {
+	cli := sampleValueCli(t)
+	tests := []struct {
+		args []string
+		out  string
+	}{
+		{[]string{"run", "--set", "a=1", "error"}, "info [a=1] false error"},
+		{[]string{"run", "--set", "a=1", "--set=b=2", "-l", "debug", "--fast", "info"}, "debug [a=1 b=2] true info"},
+		{[]string{"run", "--set", "c=3", "debug"}, "info [c=3] false debug"},
+	}
+	for _, test := range tests {
+		if code, out, errOut := execute(&cli, test.args...); code != 0 || out != test.out {
+			t.Fatalf("%v: expected %q, got %d %q %q", test.args, test.out, code, out, errOut)
+		}
+	}
+	code, _, errOut := execute(&cli, "run", "--set", "a", "--level", "loud", "info")
+	for _, want := range []string{
+		`invalid value "a" for --set, expected pair: expected key=value`,
+		`invalid value "loud" for --level, expected level: unknown level`,
+	} {
+		if code != 2 || !strings.Contains(errOut, want) {
+			t.Fatalf("expected %q, got %q", want, errOut)
+		}
+	}
+	if _, err := cli.subcommands[0].GetValue("nope"); err == nil {
+		t.Fatalf("expected an error for an unknown key")
+	}
+	if err := cli.subcommands[0].AddValueArg([]string{"other"}, "no value", nil); err == nil {
+		t.Fatalf("expected an error for a nil value")
+	}
+}
+
+func TestValueHelp(t *testing.T) {
+	cli := sampleValueCli(t)
+	_, out, _ := execute(&cli, "help", "run")
+	if !strings.Contains(out, "usage: app run --set <pair> [OPTIONS] <target>") {
+		t.Fatalf("the usage should have the type name, got %q", out)
+	}
+	labels := cli.Spec().Subcommands[0].Labels
+	if labels[1].Type != "level" || labels[1].Default != "info" {
+		t.Fatalf("unexpected spec %+v", labels[1])
+	}
+}
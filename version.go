@@ -0,0 +1,103 @@
+package goldcmd
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// Read the build information of the running binary. This is a variable so
+// that tests can replace it.
+var readBuildInfo = debug.ReadBuildInfo
+
+// Version information printed by the 'version' subcommand.
+type versionInfo struct {
+	// the name the CLI was invoked with
+	Name string `json:"name"`
+	// the version passed to NewCli
+	Version string `json:"version"`
+	// the main module path and version, from the build information
+	Module        string `json:"module,omitempty"`
+	ModuleVersion string `json:"module_version,omitempty"`
+	// the VCS revision and commit time the binary was built from
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	// whether the working tree had local modifications, if known
+	Dirty *bool `json:"dirty,omitempty"`
+	// the Go version the binary was built with
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// Enrich the version information printed by the 'version' subcommand with
+// the build information embedded in the binary: the module version, the VCS
+// revision, whether the tree was dirty, and the Go version.
+func (cli *Cli) UseBuildInfo(use bool) {
+	cli.useBuildInfo = use
+}
+
+// Get the version information for the CLI.
+func (cli *Cli) versionInfo(prog string) versionInfo {
+	info := versionInfo{Name: prog, Version: cli.version}
+	if !cli.useBuildInfo {
+		return info
+	}
+	bi, ok := readBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Module = bi.Main.Path
+	info.ModuleVersion = bi.Main.Version
+	info.GoVersion = bi.GoVersion
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.Time = setting.Value
+		case "vcs.modified":
+			dirty := setting.Value == "true"
+			info.Dirty = &dirty
+		}
+	}
+	return info
+}
+
+// Print the version information to `w`, either as text or as JSON.
+func (info versionInfo) print(w io.Writer, asJSON bool) error {
+	if asJSON {
+		return writeJSON(w, info)
+	}
+	fmt.Fprintf(w, "%s version %s\n", info.Name, info.Version)
+	if info.Module != "" {
+		fmt.Fprintf(w, "module:   %s %s\n", info.Module, info.ModuleVersion)
+	}
+	if info.Revision != "" {
+		revision := info.Revision
+		if info.Dirty != nil && *info.Dirty {
+			revision = revision + " (dirty)"
+		}
+		fmt.Fprintf(w, "revision: %s\n", revision)
+	}
+	if info.Time != "" {
+		fmt.Fprintf(w, "time:     %s\n", info.Time)
+	}
+	if info.GoVersion != "" {
+		fmt.Fprintf(w, "go:       %s\n", info.GoVersion)
+	}
+	return nil
+}
+
+// Get the built-in 'version' subcommand.
+func (cli *Cli) versionSubcommand() *SubcommandHandler {
+	h, _ := NewSubcommandHandler("version", "print the version of this app")
+	h.AddBoolParamWithDefault([]string{"json"}, "print the version as JSON", false)
+	h.Handle(func(h *SubcommandHandler) {
+		asJSON, _ := h.GetBool("json")
+		info := cli.versionInfo(h.path[0])
+		if err := info.print(h.Stdout(), asJSON); err != nil {
+			fmt.Fprintf(h.Stderr(), "error: %s\n", err)
+			h.SetExitCode(1)
+		}
+	})
+	return h
+}
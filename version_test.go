@@ -0,0 +1,49 @@
+package goldcmd
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	cli := sampleCli(t)
+	for _, args := range [][]string{{"version"}, {"--version"}, {"-V"}} {
+		code, out, _ := execute(&cli, args...)
+		if code != 0 || out != "app version 1.2.3\n" {
+			t.Fatalf("unexpected version output %q", out)
+		}
+	}
+}
+
+func TestVersionBuildInfo(t *testing.T) {
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.99",
+			Main:      debug.Module{Path: "example.com/app", Version: "v1.2.3"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc123"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		}, true
+	}
+	defer func() { readBuildInfo = debug.ReadBuildInfo }()
+	cli := sampleCli(t)
+	cli.UseBuildInfo(true)
+	_, out, _ := execute(&cli, "version")
+	want := "app version 1.2.3\n" +
+		"module:   example.com/app v1.2.3\n" +
+		"revision: abc123 (dirty)\n" +
+		"go:       go1.99\n"
+	if out != want {
+		t.Fatalf("unexpected version output %q", out)
+	}
+	_, out, _ = execute(&cli, "--version", "--json")
+	var info map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		t.Fatalf("version output should be JSON: %v", err)
+	}
+	if info["version"] != "1.2.3" || info["revision"] != "abc123" || info["dirty"] != true || info["go_version"] != "go1.99" {
+		t.Fatalf("unexpected version JSON %v", info)
+	}
+}
@@ -0,0 +1,285 @@
+package goldcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file holds a parser for the subset of YAML used by CLI specifications,
+// so the library does not need a dependency for it. It supports block
+// mappings and sequences, flow sequences of scalars e.g. "[first, f]", empty
+// flow collections, plain and quoted scalars, and comments. It does not
+// support anchors, tags, multi-line scalars, or several documents.
+//
+// Values are parsed into the same form as encoding/json with UseNumber:
+// map[string]interface{}, []interface{}, string, json.Number, bool, and nil.
+
+// A non-blank line of a YAML document, without its comment.
+type yamlLine struct {
+	// the line number, starting at 1
+	number int
+	// the number of spaces before the text
+	indent int
+	// the text of the line, without the indentation
+	text string
+}
+
+// A parser for a YAML document, which reads the lines in order.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// An error in a YAML document.
+type yamlError struct {
+	line int
+	msg  string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+// Parse a YAML document.
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{lines: make([]yamlLine, 0)}
+	for k, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripYAMLComment(strings.TrimRight(raw, "\r")), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || (len(p.lines) == 0 && trimmed == "---") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, &yamlError{k + 1, "tabs cannot be used for indentation"}
+		}
+		p.lines = append(p.lines, yamlLine{number: k + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	value, err := p.parseNode(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, &yamlError{p.lines[p.pos].number, "unexpected indentation"}
+	}
+	return value, nil
+}
+
+// Remove the comment from a line, if it has one. A comment starts with a "#"
+// at the start of the line or after a space, outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for k, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && (k == 0 || strings.ContainsRune(" [,", rune(line[k-1]))):
+			// a quote only starts a string at the start of a value, so
+			// apostrophes in plain text are not quotes
+			quote = r
+		case r == '#' && (k == 0 || line[k-1] == ' ' || line[k-1] == '\t'):
+			return line[:k]
+		}
+	}
+	return line
+}
+
+// Return true if the text of a line starts an item of a block sequence.
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// Split the text of a line into a mapping key and the rest of the line. The
+// last return value is false if the line does not start with a key.
+func splitYAMLKey(text string) (string, string, bool) {
+	var quote rune
+	for k, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case k == 0 && (r == '"' || r == '\''):
+			quote = r
+		case r == ':' && (k+1 == len(text) || text[k+1] == ' '):
+			key := strings.TrimSpace(text[:k])
+			if key == "" {
+				return "", "", false
+			}
+			if unquoted, ok := unquoteYAML(key); ok {
+				key = unquoted
+			}
+			return key, strings.TrimSpace(text[k+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// Parse the node starting at the current line, which has indentation
+// `indent`.
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	return parseYAMLFlow(line.text, line.number)
+}
+
+// Parse a block sequence whose items have indentation `indent`.
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	items := make([]interface{}, 0)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var item interface{}
+		var err error
+		if rest == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err = p.parseNode(p.lines[p.pos].indent)
+			}
+		} else {
+			// the rest of the line is parsed as if it started a line of
+			// its own, so that it can start a mapping e.g. "- name: add"
+			itemIndent := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{number: line.number, indent: itemIndent, text: rest}
+			item, err = p.parseNode(itemIndent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, &yamlError{p.lines[p.pos].number, "unexpected indentation"}
+	}
+	return items, nil
+}
+
+// Parse a block mapping whose keys have indentation `indent`.
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, &yamlError{line.number, "expected a mapping key"}
+		}
+		if _, ok := m[key]; ok {
+			return nil, &yamlError{line.number, fmt.Sprintf("duplicate key %q", key)}
+		}
+		p.pos++
+		var value interface{}
+		var err error
+		if rest != "" {
+			value, err = parseYAMLFlow(rest, line.number)
+		} else if p.pos < len(p.lines) {
+			// a nested node, or a sequence which may have the same
+			// indentation as the key
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLSequenceItem(next.text)) {
+				value, err = p.parseNode(next.indent)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, &yamlError{p.lines[p.pos].number, "unexpected indentation"}
+	}
+	return m, nil
+}
+
+// Parse a value written on one line: a scalar, a flow sequence of scalars, or
+// an empty flow mapping.
+func parseYAMLFlow(text string, line int) (interface{}, error) {
+	if text == "{}" {
+		return map[string]interface{}{}, nil
+	}
+	if !strings.HasPrefix(text, "[") {
+		return parseYAMLScalar(text, line)
+	}
+	if !strings.HasSuffix(text, "]") {
+		return nil, &yamlError{line, "unterminated flow sequence"}
+	}
+	items := make([]interface{}, 0)
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	if inner == "" {
+		return items, nil
+	}
+	var quote rune
+	start := 0
+	for k, r := range inner + "," {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && strings.TrimSpace(inner[start:k]) == "":
+			quote = r
+		case r == '[' || r == '{':
+			return nil, &yamlError{line, "nested flow collections are not supported"}
+		case r == ',':
+			item, err := parseYAMLScalar(strings.TrimSpace(inner[start:k]), line)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			start = k + 1
+		}
+	}
+	return items, nil
+}
+
+// Remove the quotes around a quoted scalar. The second return value is false
+// if the text is not quoted.
+func unquoteYAML(text string) (string, bool) {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		s, err := strconv.Unquote(text)
+		return s, err == nil
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), true
+	}
+	return "", false
+}
+
+// Parse a scalar: a quoted or plain string, a number, a Boolean, or null.
+func parseYAMLScalar(text string, line int) (interface{}, error) {
+	if text == "" {
+		return nil, &yamlError{line, "missing value"}
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		s, ok := unquoteYAML(text)
+		if !ok {
+			return nil, &yamlError{line, fmt.Sprintf("invalid quoted string %s", text)}
+		}
+		return s, nil
+	}
+	switch text {
+	case "null", "~":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if strings.ContainsAny(text[:1], "0123456789+-.") && strings.ContainsAny(text, "0123456789") {
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(strings.TrimPrefix(text, "+")), nil
+		}
+	}
+	return text, nil
+}
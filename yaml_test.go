@@ -0,0 +1,73 @@
+package goldcmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	doc := `
+name: app # the name
+quoted: "a # b"
+single: 'it''s'
+plain: it's here
+list: [first, "f", 1]
+empty: []
+nested:
+  count: 3
+  ratio: -0.5
+  on: true
+  none: ~
+outer:
+  inner:
+  - a
+  after: b
+items:
+- one
+- key: value
+  other: x
+-
+  deep: y
+`
+	got, err := parseYAML([]byte(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]interface{}{
+		"name":   "app",
+		"quoted": "a # b",
+		"single": "it's",
+		"plain":  "it's here",
+		"list":   []interface{}{"first", "f", json.Number("1")},
+		"empty":  []interface{}{},
+		"nested": map[string]interface{}{"count": json.Number("3"), "ratio": json.Number("-0.5"), "on": true, "none": nil},
+		"outer":  map[string]interface{}{"inner": []interface{}{"a"}, "after": "b"},
+		"items": []interface{}{
+			"one",
+			map[string]interface{}{"key": "value", "other": "x"},
+			map[string]interface{}{"deep": "y"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected value\n%#v\n%#v", got, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		doc string
+		err string
+	}{
+		{"a: 1\na: 2", `line 2: duplicate key "a"`},
+		{"a: 1\n  b: 2", "line 2: unexpected indentation"},
+		{"a:\n\t- b", "line 2: tabs cannot be used for indentation"},
+		{"a: [b, c", "line 1: unterminated flow sequence"},
+		{"a:\n  - b\n  c: d", "line 3: unexpected indentation"},
+	}
+	for _, test := range tests {
+		if _, err := parseYAML([]byte(test.doc)); err == nil || err.Error() != test.err {
+			t.Fatalf("expected error %q for %q, got %v", test.err, test.doc, err)
+		}
+	}
+}